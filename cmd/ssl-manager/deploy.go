@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/core"
+	"ssl-manager/internal/daemon"
+)
+
+func handleDeploy(configPath string) {
+	usage := "用法: ssl-manager [config.yaml] deploy <域名> [部署目标]  # 重新部署当前证书，未指定目标时部署到所有目标"
+	if len(os.Args) < 4 {
+		log.Fatal(usage)
+	}
+	domain := os.Args[3]
+	target := ""
+	if len(os.Args) > 4 {
+		target = os.Args[4]
+	}
+
+	// 加载配置
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 创建管理器
+	manager, err := core.NewManager(cfg)
+	if err != nil {
+		log.Fatalf("初始化失败: %v", err)
+	}
+
+	// 信号处理
+	sigHandler := daemon.NewSignalHandler()
+	sigHandler.Start()
+
+	results, err := manager.Deploy(sigHandler.Context(), domain, target)
+	if err != nil {
+		log.Fatalf("部署失败: %v", err)
+	}
+
+	failed := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "部署目标\t类型\t结果\t尝试次数\t错误")
+	for _, r := range results {
+		status, reason := "成功", ""
+		if r.Err != nil {
+			status, reason = "失败", r.Err.Error()
+			failed = true
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", r.Target, r.Type, status, r.Attempts, orDash(reason))
+	}
+	w.Flush()
+	if failed {
+		os.Exit(1)
+	}
+}
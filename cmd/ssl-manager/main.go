@@ -22,6 +22,14 @@ func printUsage() {
   ssl-manager [config.yaml] status                             # 查看运行状态
   ssl-manager [config.yaml] daemon                             # 前台守护进程模式（调试用）
   ssl-manager [config.yaml] continue <订单ID> <域名> [提供商]  # 继续处理已有订单
+  ssl-manager [config.yaml] orders list [域名|提供商]           # 列出证书订单
+  ssl-manager [config.yaml] orders show <订单ID> [域名|提供商]  # 查看订单详情
+  ssl-manager [config.yaml] orders cancel <订单ID> [域名|提供商] # 取消订单
+  ssl-manager [config.yaml] prune-cloud [--older-than 30d] [--dry-run] [--all]  # 清理云端过期/被替代的证书
+  ssl-manager [config.yaml] revoke <域名|证书ID> [--reason unspecified]          # 吊销证书并立即重新签发
+  ssl-manager [config.yaml] verify [域名]                      # 校验线上端点是否已部署本地证书
+  ssl-manager [config.yaml] rollback <域名> [版本|序列号|--list] # 回滚到历史版本并重新部署
+  ssl-manager [config.yaml] deploy <域名> [部署目标]           # 重新部署当前证书到部署目标
 
 示例:
   ssl-manager                          # 使用默认配置，单次运行
@@ -94,6 +102,24 @@ func main() {
 	case "continue":
 		handleContinue(configPath)
 		return
+	case "orders":
+		handleOrders(configPath)
+		return
+	case "prune-cloud":
+		handlePruneCloud(configPath)
+		return
+	case "revoke":
+		handleRevoke(configPath)
+		return
+	case "verify":
+		handleVerify(configPath)
+		return
+	case "rollback":
+		handleRollback(configPath)
+		return
+	case "deploy":
+		handleDeploy(configPath)
+		return
 	}
 
 	// 默认：单次运行
@@ -128,11 +154,6 @@ func handleRestart(configPath string) {
 	}
 }
 
-func handleStatus(configPath string) {
-	d := daemon.NewDaemon(configPath)
-	d.Status()
-}
-
 func runDaemonBackground(configPath string, d *daemon.Daemon) {
 	// 写入 PID
 	if err := d.WritePid(); err != nil {
@@ -164,10 +185,14 @@ func runDaemonBackground(configPath string, d *daemon.Daemon) {
 	if err := manager.Run(ctx); err != nil {
 		log.Printf("运行出错: %v", err)
 	}
+	manager.CheckDrift(ctx)
+	manager.RefreshOCSP(ctx)
 
 	// 主循环
 	ticker := time.NewTicker(time.Duration(cfg.CheckInterval) * time.Hour)
 	defer ticker.Stop()
+	ocspTick := newOCSPTicker(cfg)
+	defer ocspTick.Stop()
 
 	for {
 		select {
@@ -179,6 +204,9 @@ func runDaemonBackground(configPath string, d *daemon.Daemon) {
 			if err := manager.Run(ctx); err != nil {
 				log.Printf("运行出错: %v", err)
 			}
+			manager.CheckDrift(ctx)
+		case <-ocspTick.C:
+			manager.RefreshOCSP(ctx)
 		}
 	}
 }
@@ -208,10 +236,14 @@ func runDaemonForeground(configPath string) {
 	if err := manager.Run(ctx); err != nil {
 		log.Printf("运行出错: %v", err)
 	}
+	manager.CheckDrift(ctx)
+	manager.RefreshOCSP(ctx)
 
 	// 主循环
 	ticker := time.NewTicker(time.Duration(cfg.CheckInterval) * time.Hour)
 	defer ticker.Stop()
+	ocspTick := newOCSPTicker(cfg)
+	defer ocspTick.Stop()
 
 	for {
 		select {
@@ -223,6 +255,9 @@ func runDaemonForeground(configPath string) {
 			if err := manager.Run(ctx); err != nil {
 				log.Printf("运行出错: %v", err)
 			}
+			manager.CheckDrift(ctx)
+		case <-ocspTick.C:
+			manager.RefreshOCSP(ctx)
 		}
 	}
 }
@@ -235,20 +270,24 @@ func handleContinue(configPath string) {
 	orderID := os.Args[3]
 	domain := os.Args[4]
 
-	// 默认使用阿里云
-	certProvider := "aliyun"
-	dnsProvider := "aliyun"
-	if len(os.Args) > 5 {
-		certProvider = os.Args[5]
-		dnsProvider = os.Args[5]
-	}
-
 	// 加载配置
 	cfg, err := config.Load(configPath)
 	if err != nil {
 		log.Fatalf("加载配置失败: %v", err)
 	}
 
+	// 优先使用命令行指定的提供商，否则使用域名配置中的提供商
+	var certProvider, dnsProvider string
+	if len(os.Args) > 5 {
+		certProvider = os.Args[5]
+		dnsProvider = os.Args[5]
+	} else if domainCfg := cfg.FindDomain(domain); domainCfg != nil {
+		certProvider = domainCfg.GetCertProvider()
+		dnsProvider = domainCfg.GetDNSProvider()
+	} else {
+		log.Fatalf("域名 %s 不在配置中，请指定提供商", domain)
+	}
+
 	// 创建管理器
 	manager, err := core.NewManager(cfg)
 	if err != nil {
@@ -289,4 +328,15 @@ func runOnce(configPath string) {
 	if err := manager.Run(ctx); err != nil {
 		log.Fatalf("运行出错: %v", err)
 	}
+	manager.RefreshOCSP(ctx)
+}
+
+// newOCSPTicker 创建刷新 OCSP 响应的定时器，未开启 OCSP 检查时定时器不会触发
+func newOCSPTicker(cfg *config.Config) *time.Ticker {
+	if cfg.OCSP == nil || !cfg.OCSP.Enabled {
+		ticker := time.NewTicker(time.Hour)
+		ticker.Stop()
+		return ticker
+	}
+	return time.NewTicker(time.Duration(cfg.OCSP.Interval) * time.Hour)
 }
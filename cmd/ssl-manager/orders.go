@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/core"
+	"ssl-manager/internal/daemon"
+	"ssl-manager/internal/provider"
+)
+
+// 支持的云平台名称
+var providerNames = map[string]bool{
+	"aliyun":  true,
+	"tencent": true,
+	"huawei":  true,
+}
+
+// resolveProviders 根据参数解析证书和DNS提供商
+// arg 可以是配置中的域名或提供商名称；为空时，若所有域名使用同一证书提供商则使用该提供商
+func resolveProviders(cfg *config.Config, arg string) (certProvider, dnsProvider string, err error) {
+	if arg != "" {
+		if domainCfg := cfg.FindDomain(arg); domainCfg != nil {
+			return domainCfg.GetCertProvider(), domainCfg.GetDNSProvider(), nil
+		}
+		if providerNames[arg] {
+			return arg, arg, nil
+		}
+		return "", "", fmt.Errorf("%s 既不是已配置的域名，也不是支持的提供商", arg)
+	}
+
+	names := cfg.CertProviderNames()
+	if len(names) != 1 {
+		return "", "", fmt.Errorf("配置中使用了多个证书提供商 %v，请指定域名或提供商", names)
+	}
+	return names[0], names[0], nil
+}
+
+func handleOrders(configPath string) {
+	usage := `用法:
+  ssl-manager [config.yaml] orders list [域名|提供商]
+  ssl-manager [config.yaml] orders show <订单ID> [域名|提供商]
+  ssl-manager [config.yaml] orders cancel <订单ID> [域名|提供商]`
+
+	if len(os.Args) < 4 {
+		log.Fatal(usage)
+	}
+	subcommand := os.Args[3]
+
+	// 加载配置
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 创建管理器
+	manager, err := core.NewManager(cfg)
+	if err != nil {
+		log.Fatalf("初始化失败: %v", err)
+	}
+
+	// 信号处理
+	sigHandler := daemon.NewSignalHandler()
+	sigHandler.Start()
+
+	ctx := sigHandler.Context()
+
+	switch subcommand {
+	case "list":
+		arg := ""
+		if len(os.Args) > 4 {
+			arg = os.Args[4]
+		}
+
+		// 未指定时列出所有已配置的证书提供商的订单
+		certProviders := cfg.CertProviderNames()
+		domainFilter := ""
+		if arg != "" {
+			certProvider, _, err := resolveProviders(cfg, arg)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			certProviders = []string{certProvider}
+			if cfg.FindDomain(arg) != nil {
+				domainFilter = arg
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "提供商\t订单ID\t域名\t状态\t创建时间")
+		for _, name := range certProviders {
+			orders, err := manager.ListOrders(ctx, name)
+			if err != nil {
+				log.Printf("获取 %s 订单列表失败: %v", name, err)
+				continue
+			}
+			for _, order := range orders {
+				if domainFilter != "" && order.Domain != domainFilter {
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, order.OrderID, order.Domain, order.Status, formatOrderTime(order))
+			}
+		}
+		w.Flush()
+
+	case "show", "cancel":
+		if len(os.Args) < 5 {
+			log.Fatal(usage)
+		}
+		orderID := os.Args[4]
+		arg := ""
+		if len(os.Args) > 5 {
+			arg = os.Args[5]
+		}
+
+		certProvider, _, err := resolveProviders(cfg, arg)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		if subcommand == "cancel" {
+			if err := manager.CancelOrder(ctx, certProvider, orderID); err != nil {
+				log.Fatalf("取消订单失败: %v", err)
+			}
+			fmt.Printf("订单 %s 已取消 (提供商: %s)\n", orderID, certProvider)
+			return
+		}
+
+		status, err := manager.GetOrder(ctx, certProvider, orderID)
+		if err != nil {
+			log.Fatalf("查询订单失败: %v", err)
+		}
+		fmt.Printf("提供商:       %s\n", certProvider)
+		fmt.Printf("订单ID:       %s\n", status.OrderID)
+		fmt.Printf("状态:         %s\n", status.Status)
+		if status.Domain != "" {
+			fmt.Printf("域名:         %s\n", status.Domain)
+		}
+		if status.RecordDomain != "" {
+			fmt.Printf("验证记录名:   %s\n", status.RecordDomain)
+			fmt.Printf("验证记录类型: %s\n", status.RecordType)
+			fmt.Printf("验证记录值:   %s\n", status.RecordValue)
+		}
+
+	default:
+		log.Fatal(usage)
+	}
+}
+
+// formatOrderTime 格式化订单创建时间
+func formatOrderTime(order *provider.OrderInfo) string {
+	if order.CreatedAt.IsZero() {
+		return "-"
+	}
+	return order.CreatedAt.Format("2006-01-02 15:04:05")
+}
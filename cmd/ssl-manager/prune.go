@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/core"
+	"ssl-manager/internal/daemon"
+)
+
+func handlePruneCloud(configPath string) {
+	fs := flag.NewFlagSet("prune-cloud", flag.ExitOnError)
+	olderThan := fs.String("older-than", "30d", "清理过期或被替代超过该时长的证书 (如 30d, 72h)")
+	dryRun := fs.Bool("dry-run", false, "只列出将要删除的证书，不实际删除")
+	all := fs.Bool("all", false, "清理账号下所有证书，而不仅是配置中的域名")
+	fs.Parse(os.Args[3:])
+
+	duration, err := parseDuration(*olderThan)
+	if err != nil {
+		log.Fatalf("无效的 --older-than: %v", err)
+	}
+
+	// 加载配置
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 创建管理器
+	manager, err := core.NewManager(cfg)
+	if err != nil {
+		log.Fatalf("初始化失败: %v", err)
+	}
+
+	// 信号处理
+	sigHandler := daemon.NewSignalHandler()
+	sigHandler.Start()
+
+	ctx := sigHandler.Context()
+
+	results, err := manager.PruneCloud(ctx, core.PruneOptions{
+		OlderThan: duration,
+		DryRun:    *dryRun,
+		All:       *all,
+	})
+	if err != nil {
+		log.Printf("清理证书出错: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "提供商\t证书ID\t域名\t到期时间\t原因\t结果")
+	var deleted, planned int
+	for _, result := range results {
+		var outcome string
+		switch {
+		case result.Err != nil:
+			outcome = fmt.Sprintf("失败: %v", result.Err)
+		case result.Skipped():
+			outcome = fmt.Sprintf("跳过: 仍绑定 %s", strings.Join(result.Resources, ", "))
+		case result.Deleted:
+			outcome = "已删除"
+			deleted++
+		default:
+			outcome = "将删除"
+			planned++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			result.Provider, result.Cert.CertID, result.Cert.Domain,
+			result.Cert.NotAfter.Format("2006-01-02"), result.Reason, outcome)
+	}
+	w.Flush()
+
+	if *dryRun {
+		fmt.Printf("\n[dry-run] 共 %d 个证书将被删除\n", planned)
+	} else {
+		fmt.Printf("\n共删除 %d 个证书\n", deleted)
+	}
+}
+
+// parseDuration 解析时长，除 Go 标准格式外还支持以 d 结尾的天数（如 30d）
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("无法解析天数: %s", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
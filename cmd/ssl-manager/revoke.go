@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/core"
+	"ssl-manager/internal/daemon"
+)
+
+// 吊销原因（RFC 5280 CRLReason）
+var revokeReasons = map[string]bool{
+	"unspecified":          true,
+	"keyCompromise":        true,
+	"caCompromise":         true,
+	"affiliationChanged":   true,
+	"superseded":           true,
+	"cessationOfOperation": true,
+	"privilegeWithdrawn":   true,
+}
+
+func handleRevoke(configPath string) {
+	usage := "用法: ssl-manager [config.yaml] revoke <域名|证书ID> [--reason unspecified]"
+	if len(os.Args) < 4 {
+		log.Fatal(usage)
+	}
+	target := os.Args[3]
+
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	reason := fs.String("reason", "unspecified", "吊销原因 (RFC 5280)，私钥泄露时使用 keyCompromise")
+	fs.Parse(os.Args[4:])
+
+	if !revokeReasons[*reason] {
+		log.Fatalf("不支持的吊销原因: %s", *reason)
+	}
+
+	// 加载配置
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 创建管理器
+	manager, err := core.NewManager(cfg)
+	if err != nil {
+		log.Fatalf("初始化失败: %v", err)
+	}
+
+	// 信号处理
+	sigHandler := daemon.NewSignalHandler()
+	sigHandler.Start()
+
+	ctx := sigHandler.Context()
+
+	if err := manager.RevokeCertificate(ctx, target, *reason); err != nil {
+		log.Fatalf("吊销证书失败: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/core"
+)
+
+func handleRollback(configPath string) {
+	usage := `用法:
+  ssl-manager [config.yaml] rollback <域名> [版本|序列号]  # 回滚到指定版本，未指定时回滚到上一个版本
+  ssl-manager [config.yaml] rollback <域名> --list        # 列出历史版本`
+
+	if len(os.Args) < 4 {
+		log.Fatal(usage)
+	}
+	domain := os.Args[3]
+	version := ""
+	if len(os.Args) > 4 {
+		version = os.Args[4]
+	}
+
+	// 加载配置
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 创建管理器
+	manager, err := core.NewManager(cfg)
+	if err != nil {
+		log.Fatalf("初始化失败: %v", err)
+	}
+
+	if version == "--list" {
+		versions, err := manager.History(domain)
+		if err != nil {
+			log.Fatalf("获取历史版本失败: %v", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "版本\t序列号\t生效时间\t到期时间\t保存时间\t当前")
+		for _, v := range versions {
+			current := ""
+			if v.Current {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				orDash(v.Name), v.Serial, v.NotBefore.Format("2006-01-02"), v.NotAfter.Format("2006-01-02"),
+				v.SavedAt.Format("2006-01-02 15:04:05"), current)
+		}
+		w.Flush()
+		return
+	}
+
+	result, err := manager.Rollback(context.Background(), domain, version)
+	if err != nil {
+		log.Fatalf("回滚失败: %v", err)
+	}
+	fmt.Printf("%s 已回滚到序列号 %s (原序列号: %s)\n", domain, result.Serial, result.PreviousSerial)
+}
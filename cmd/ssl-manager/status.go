@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/core"
+	"ssl-manager/internal/daemon"
+)
+
+func handleStatus(configPath string) {
+	d := daemon.NewDaemon(configPath)
+	d.Status()
+
+	// 配置无法加载时只显示守护进程状态
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Printf("\n加载配置失败，无法显示证书状态: %v\n", err)
+		return
+	}
+	manager, err := core.NewManager(cfg)
+	if err != nil {
+		fmt.Printf("\n初始化失败，无法显示证书状态: %v\n", err)
+		return
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "域名\t序列号\t签发者\t密钥类型\t到期时间\t剩余天数\t证书ID\t包含域名")
+	for _, domainCfg := range cfg.Domains {
+		meta, err := manager.Metadata(domainCfg.Domain)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t(无证书)\t-\t-\t-\t-\t-\t-\n", domainCfg.Domain)
+			continue
+		}
+		days := int(time.Until(meta.NotAfter).Hours() / 24)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domainCfg.Domain, meta.Serial, orDash(meta.Issuer), orDash(meta.KeyType),
+			meta.NotAfter.Format("2006-01-02"), days, orDash(meta.CertID), strings.Join(meta.SANs, ","))
+	}
+	w.Flush()
+
+	printDeployments(manager, cfg)
+}
+
+// printDeployments 显示各部署目标最近一次的部署结果
+func printDeployments(manager *core.Manager, cfg *config.Config) {
+	header := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, domainCfg := range cfg.Domains {
+		for i := range domainCfg.Deploy {
+			if !header {
+				fmt.Println()
+				fmt.Fprintln(w, "域名\t部署目标\t类型\t序列号\t结果\t部署时间\t错误")
+				header = true
+			}
+			target := &domainCfg.Deploy[i]
+			name := target.GetName(i)
+			record := manager.State().Deployment(domainCfg.Domain, name)
+			if record == nil {
+				fmt.Fprintf(w, "%s\t%s\t%s\t-\t(未部署)\t-\t-\n", domainCfg.Domain, name, target.Type)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				domainCfg.Domain, name, record.Type, record.Serial, record.Status,
+				record.DeployedAt.Format("2006-01-02 15:04:05"), orDash(record.Error))
+		}
+	}
+	w.Flush()
+}
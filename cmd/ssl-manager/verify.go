@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/core"
+	"ssl-manager/internal/daemon"
+)
+
+func handleVerify(configPath string) {
+	domain := ""
+	if len(os.Args) > 3 {
+		domain = os.Args[3]
+	}
+
+	// 加载配置
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 创建管理器
+	manager, err := core.NewManager(cfg)
+	if err != nil {
+		log.Fatalf("初始化失败: %v", err)
+	}
+
+	// 信号处理
+	sigHandler := daemon.NewSignalHandler()
+	sigHandler.Start()
+
+	ctx := sigHandler.Context()
+
+	results, err := manager.Verify(ctx, domain)
+	if err != nil {
+		log.Printf("校验出错: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "域名\t端点\t本地序列号\t线上序列号\t线上到期时间\t结果")
+	var problems int
+	for _, result := range results {
+		var outcome, notAfter string
+		switch {
+		case result.Err != nil:
+			outcome = fmt.Sprintf("失败: %v", result.Err)
+			problems++
+		case result.Drift:
+			outcome = "不一致"
+			problems++
+		default:
+			outcome = "一致"
+		}
+		if !result.ServedNotAfter.IsZero() {
+			notAfter = result.ServedNotAfter.Format("2006-01-02")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			result.Domain, orDash(result.Endpoint), orDash(result.ExpectedSerial),
+			orDash(result.ServedSerial), orDash(notAfter), outcome)
+	}
+	w.Flush()
+
+	if problems > 0 {
+		fmt.Printf("\n共 %d 个端点未使用本地证书或检查失败\n", problems)
+		os.Exit(1)
+	}
+}
+
+// orDash 空值显示为 -
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
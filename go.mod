@@ -3,6 +3,9 @@ module ssl-manager
 go 1.24.0
 
 require (
+	// 私钥加密 (age)
+	filippo.io/age v1.2.1
+
 	// 阿里云SDK
 	github.com/alibabacloud-go/alidns-20150109/v4 v4.7.0
 	github.com/alibabacloud-go/cas-20200407/v3 v3.0.4
@@ -12,13 +15,25 @@ require (
 	// 华为云SDK
 	github.com/huaweicloud/huaweicloud-sdk-go-v3 v0.1.127
 
+	// Java KeyStore 生成
+	github.com/pavlo-v-chernykh/keystore-go/v4 v4.5.0
+
 	// 腾讯云SDK
 	github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common v1.0.1046
 	github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/dnspod v1.0.1046
 	github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ssl v1.0.1046
 
+	// OCSP 查询
+	golang.org/x/crypto v0.24.0
+
+	// 代理拨号 (SOCKS5)
+	golang.org/x/net v0.26.0
+
 	// YAML解析
 	gopkg.in/yaml.v3 v3.0.1
+
+	// PKCS#12 生成
+	software.sslmate.com/src/go-pkcs12 v0.7.3
 )
 
 require (
@@ -35,7 +50,6 @@ require (
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/tjfoc/gmsm v1.4.1 // indirect
 	go.mongodb.org/mongo-driver v1.12.0 // indirect
-	golang.org/x/crypto v0.24.0 // indirect
-	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 )
@@ -0,0 +1,107 @@
+package certutil
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseCertificatePEM 解析PEM内容中的第一个证书（叶子证书）
+func ParseCertificatePEM(pemData string) (*x509.Certificate, error) {
+	certs, err := ParseCertificatesPEM(pemData)
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// ParseCertificatesPEM 按顺序解析PEM内容中的所有证书
+func ParseCertificatesPEM(pemData string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(pemData)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析证书失败: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("未找到PEM格式的证书")
+	}
+	return certs, nil
+}
+
+// ParsePrivateKeyPEM 解析PEM格式的私钥，支持 PKCS#1、PKCS#8 和 SEC 1 (EC) 编码
+func ParsePrivateKeyPEM(pemData string) (crypto.Signer, error) {
+	rest := []byte(pemData)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("未找到PEM格式的私钥")
+		}
+
+		var key any
+		var err error
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("不支持的私钥类型: %T", key)
+		}
+		return signer, nil
+	}
+}
+
+// LoadCertPool 读取PEM格式的CA证书文件，path 为空时返回 nil（使用系统根证书）
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取CA证书文件失败: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA证书文件中未找到PEM格式的证书: %s", path)
+	}
+	return pool, nil
+}
+
+// Fingerprint 返回证书的 SHA-256 指纹（小写十六进制）
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// SerialHex 返回证书序列号（小写十六进制）
+func SerialHex(cert *x509.Certificate) string {
+	return strings.ToLower(cert.SerialNumber.Text(16))
+}
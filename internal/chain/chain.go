@@ -0,0 +1,222 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/provider"
+)
+
+// maxDepth 证书链的最大长度，防止签发关系成环时无限循环
+const maxDepth = 10
+
+// maxResponseSize AIA 下载的证书最大字节数
+const maxResponseSize = 1 << 20
+
+// Completer 证书链补全器
+// 整理云平台返回的证书链（叶子证书 → 中间证书，去掉根证书），缺少的中间证书通过 AIA 地址下载并缓存到本地
+type Completer struct {
+	client   *http.Client
+	cacheDir string
+}
+
+// NewCompleter 创建证书链补全器，cacheDir 为中间证书缓存目录（为空时不缓存），client 为 nil 时使用默认客户端
+func NewCompleter(cacheDir string, client *http.Client) *Completer {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Completer{client: client, cacheDir: cacheDir}
+}
+
+// Complete 返回证书链整理后的证书：Certificate 只包含叶子证书，Chain 为叶子证书加中间证书（不含根证书）
+// 无法补全时保留已有的中间证书并记录日志，由保存前的校验拒绝不完整的证书链
+func (c *Completer) Complete(ctx context.Context, cert *provider.Certificate) (*provider.Certificate, error) {
+	leafCerts, err := certutil.ParseCertificatesPEM(cert.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书失败: %w", err)
+	}
+	// 部分云平台的证书字段是叶子证书和中间证书拼接在一起的，两个字段中的证书都作为候选
+	candidates := leafCerts
+	if cert.Chain != "" {
+		chainCerts, err := certutil.ParseCertificatesPEM(cert.Chain)
+		if err != nil {
+			return nil, fmt.Errorf("解析证书链失败: %w", err)
+		}
+		candidates = append(candidates, chainCerts...)
+	}
+	leaf := findLeaf(leafCerts)
+
+	chain := []*x509.Certificate{leaf}
+	current := leaf
+	for len(chain) < maxDepth && !isSelfSigned(current) {
+		issuer := findIssuer(current, candidates)
+		if issuer == nil {
+			issuer = c.loadCached(current)
+		}
+		if issuer == nil {
+			if issuer, err = c.fetch(ctx, current); err != nil {
+				log.Printf("下载中间证书失败 (%s): %v", current.Subject.CommonName, err)
+				break
+			}
+			if issuer == nil {
+				break
+			}
+		}
+		if isSelfSigned(issuer) {
+			// 根证书由客户端的信任库提供，不放入证书链
+			break
+		}
+		chain = append(chain, issuer)
+		current = issuer
+	}
+
+	var fullchain bytes.Buffer
+	for _, link := range chain {
+		pem.Encode(&fullchain, &pem.Block{Type: "CERTIFICATE", Bytes: link.Raw})
+	}
+	completed := *cert
+	completed.Certificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}))
+	completed.Chain = fullchain.String()
+	return &completed, nil
+}
+
+// findLeaf 返回证书字段中的叶子证书（第一个非 CA 证书，都是 CA 证书时为第一个）
+func findLeaf(certs []*x509.Certificate) *x509.Certificate {
+	for _, c := range certs {
+		if !c.IsCA {
+			return c
+		}
+	}
+	return certs[0]
+}
+
+// findIssuer 在候选证书中查找签发了 cert 的证书
+func findIssuer(cert *x509.Certificate, candidates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range candidates {
+		if candidate.Equal(cert) {
+			continue
+		}
+		if isIssuer(cert, candidate) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// isIssuer 检查 issuer 是否为 cert 的签发者（主题匹配且签名有效）
+func isIssuer(cert, issuer *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, issuer.RawSubject) && cert.CheckSignatureFrom(issuer) == nil
+}
+
+// isSelfSigned 检查是否为自签名证书（根证书）
+func isSelfSigned(cert *x509.Certificate) bool {
+	return isIssuer(cert, cert)
+}
+
+// cachePath 返回 cert 的签发者在缓存目录中的路径，按颁发机构密钥标识（没有时为签发者主题）索引
+func (c *Completer) cachePath(cert *x509.Certificate) string {
+	id := cert.AuthorityKeyId
+	if len(id) == 0 {
+		sum := sha256.Sum256(cert.RawIssuer)
+		id = sum[:]
+	}
+	return filepath.Join(c.cacheDir, hex.EncodeToString(id)+".pem")
+}
+
+// loadCached 从缓存目录读取 cert 的签发者
+func (c *Completer) loadCached(cert *x509.Certificate) *x509.Certificate {
+	if c.cacheDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.cachePath(cert))
+	if err != nil {
+		return nil
+	}
+	issuer, err := certutil.ParseCertificatePEM(string(data))
+	if err != nil || !isIssuer(cert, issuer) {
+		return nil
+	}
+	return issuer
+}
+
+// saveCached 将下载的签发者证书写入缓存目录
+func (c *Completer) saveCached(cert, issuer *x509.Certificate) {
+	if c.cacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(c.cacheDir, 0755); err != nil {
+		log.Printf("创建中间证书缓存目录失败: %v", err)
+		return
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuer.Raw})
+	if err := os.WriteFile(c.cachePath(cert), data, 0644); err != nil {
+		log.Printf("缓存中间证书失败: %v", err)
+	}
+}
+
+// fetch 通过 AIA 中的 CA Issuers 地址下载 cert 的签发者，证书没有 AIA 地址时返回 nil
+func (c *Completer) fetch(ctx context.Context, cert *x509.Certificate) (*x509.Certificate, error) {
+	if len(cert.IssuingCertificateURL) == 0 {
+		return nil, nil
+	}
+
+	var lastErr error
+	for _, url := range cert.IssuingCertificateURL {
+		issuer, err := c.download(ctx, url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !isIssuer(cert, issuer) {
+			lastErr = fmt.Errorf("%s 返回的证书不是签发者证书 (%s)", url, issuer.Subject.CommonName)
+			continue
+		}
+		log.Printf("已通过 AIA 下载中间证书: %s", issuer.Subject.CommonName)
+		c.saveCached(cert, issuer)
+		return issuer, nil
+	}
+	return nil, lastErr
+}
+
+// download 下载 AIA 地址上的证书（DER 或 PEM 编码）
+func (c *Completer) download(ctx context.Context, url string) (*x509.Certificate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 %s 失败: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求 %s 返回错误状态码: %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", url, err)
+	}
+
+	if strings.Contains(string(data), "-----BEGIN CERTIFICATE-----") {
+		return certutil.ParseCertificatePEM(string(data))
+	}
+	issuer, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 返回的证书失败: %w", url, err)
+	}
+	return issuer, nil
+}
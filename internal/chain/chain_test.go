@@ -0,0 +1,165 @@
+package chain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/provider"
+)
+
+// testIssuer 测试用 CA 证书及私钥
+type testIssuer struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newCert 签发证书，parent 为 nil 时生成自签名根证书
+func newCert(t *testing.T, name string, isCA bool, parent *testIssuer, aia string) *testIssuer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if aia != "" {
+		tmpl.IssuingCertificateURL = []string{aia}
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testIssuer{cert: cert, key: key}
+}
+
+// aiaServer 测试用 AIA 服务，按路径返回 DER 编码的证书并记录请求次数
+type aiaServer struct {
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+	hits  map[string]int
+}
+
+func (s *aiaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hits[r.URL.Path]++
+	cert, ok := s.certs[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pkix-cert")
+	w.Write(cert.Raw)
+}
+
+func (s *aiaServer) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+func encodePEM(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+func TestCompleteFetchesAndCachesIssuer(t *testing.T) {
+	aia := &aiaServer{certs: map[string]*x509.Certificate{}, hits: map[string]int{}}
+	server := httptest.NewServer(aia)
+	defer server.Close()
+
+	root := newCert(t, "Test Root", true, nil, "")
+	intermediate := newCert(t, "Test Intermediate", true, root, server.URL+"/root.der")
+	leaf := newCert(t, "www.example.com", false, intermediate, server.URL+"/intermediate.der")
+	aia.certs["/root.der"] = root.cert
+	aia.certs["/intermediate.der"] = intermediate.cert
+
+	cacheDir := t.TempDir()
+	cert := &provider.Certificate{Certificate: encodePEM(leaf.cert)}
+	completed, err := NewCompleter(cacheDir, server.Client()).Complete(context.Background(), cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := encodePEM(leaf.cert) + encodePEM(intermediate.cert)
+	if completed.Chain != want {
+		t.Fatalf("证书链缺少中间证书或包含根证书:\n%s", completed.Chain)
+	}
+	if aia.count("/intermediate.der") != 1 || aia.count("/root.der") != 1 {
+		t.Fatalf("AIA 请求次数 = %v", aia.hits)
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("缓存目录中有 %d 个证书, 期望 2 个", len(entries))
+	}
+
+	// 新的补全器从缓存读取签发者，不再请求 AIA 地址
+	completed, err = NewCompleter(cacheDir, server.Client()).Complete(context.Background(), cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed.Chain != want {
+		t.Fatalf("使用缓存补全的证书链不一致:\n%s", completed.Chain)
+	}
+	if aia.count("/intermediate.der") != 1 || aia.count("/root.der") != 1 {
+		t.Fatalf("命中缓存后仍请求了 AIA 地址: %v", aia.hits)
+	}
+}
+
+func TestCompleteRejectsWrongIssuer(t *testing.T) {
+	aia := &aiaServer{certs: map[string]*x509.Certificate{}, hits: map[string]int{}}
+	server := httptest.NewServer(aia)
+	defer server.Close()
+
+	root := newCert(t, "Test Root", true, nil, "")
+	intermediate := newCert(t, "Test Intermediate", true, root, "")
+	leaf := newCert(t, "www.example.com", false, intermediate, server.URL+"/intermediate.der")
+	aia.certs["/intermediate.der"] = newCert(t, "Other Intermediate", true, root, "").cert
+
+	cacheDir := t.TempDir()
+	completed, err := NewCompleter(cacheDir, server.Client()).Complete(context.Background(), &provider.Certificate{Certificate: encodePEM(leaf.cert)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := certutil.ParseCertificatesPEM(completed.Chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 1 {
+		t.Fatalf("证书链包含 %d 个证书, 不应加入签名不匹配的证书", len(chain))
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+		t.Fatalf("签名不匹配的证书被缓存: %v", entries)
+	}
+}
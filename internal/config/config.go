@@ -1,5 +1,12 @@
 package config
 
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
 // Config 配置结构
 type Config struct {
 	// 云平台凭证配置
@@ -8,19 +15,99 @@ type Config struct {
 	// 域名配置
 	Domains []DomainConfig `yaml:"domains"`
 
+	// 证书存储配置（未配置时使用 output_dir 下的文件存储）
+	Storage StorageConfig `yaml:"storage,omitempty"`
+
 	// 全局配置
 	OutputDir     string `yaml:"output_dir"`
 	CheckInterval int    `yaml:"check_interval"` // 检查间隔（小时）
 	PostCommand   string `yaml:"post_command"`   // 全局后置命令
 	Concurrency   int    `yaml:"concurrency"`    // 并发处理数，默认1
+	RenewSource   string `yaml:"renew_source"`   // 全局续期判断依据: local, live, both，默认 live
+	DriftGrace    int    `yaml:"drift_grace"`    // 证书更新后多久（小时）线上仍为旧证书时告警，默认24
+	TrustBundle   string `yaml:"trust_bundle"`   // 保存前校验证书链使用的CA证书文件，默认使用系统根证书
 
 	// Webhook 通知配置
 	Webhook *WebhookConfig `yaml:"webhook,omitempty"`
 
+	// OCSP 状态检查配置
+	OCSP *OCSPConfig `yaml:"ocsp,omitempty"`
+
 	// 向后兼容：旧版阿里云配置
 	Aliyun *AliyunConfig `yaml:"aliyun,omitempty"`
 }
 
+// 存储后端类型
+const (
+	StorageFile  = "file"
+	StorageS3    = "s3"
+	StorageVault = "vault"
+)
+
+// StorageConfig 证书存储配置
+type StorageConfig struct {
+	Type      string       `yaml:"type,omitempty"`      // 存储后端: file, s3, vault，默认 file
+	Path      string       `yaml:"path,omitempty"`      // 本地证书目录（s3/vault 后端作为本地缓存，供后置命令使用），默认为 output_dir
+	Retention int          `yaml:"retention,omitempty"` // 本地保留的证书历史版本数，默认 5
+	S3        *S3Config    `yaml:"s3,omitempty"`        // S3 兼容对象存储配置
+	Vault     *VaultConfig `yaml:"vault,omitempty"`     // HashiCorp Vault 配置
+
+	Encryption *EncryptionConfig `yaml:"encryption,omitempty"` // 本地私钥加密配置（file 和 s3 后端）
+}
+
+// 私钥加密方式
+const (
+	EncryptionPKCS8 = "pkcs8"
+	EncryptionAge   = "age"
+)
+
+// EncryptionConfig 本地私钥加密配置
+// 启用后 key.pem 加密保存，明文私钥只在部署时写入 decrypt_dir 或 layout 配置的位置
+type EncryptionConfig struct {
+	Type           string   `yaml:"type"`                      // 加密方式: pkcs8, age
+	PassphraseEnv  string   `yaml:"passphrase_env,omitempty"`  // pkcs8: 读取口令的环境变量
+	PassphraseFile string   `yaml:"passphrase_file,omitempty"` // pkcs8: 读取口令的文件
+	Recipients     []string `yaml:"recipients,omitempty"`      // age: 接收者公钥 (age1...)
+	IdentityFile   string   `yaml:"identity_file,omitempty"`   // age: 身份私钥文件，用于解密
+	DecryptDir     string   `yaml:"decrypt_dir,omitempty"`     // 部署时写入明文私钥的目录（应为 tmpfs），默认 /dev/shm/ssl-manager
+}
+
+// S3Config S3 兼容对象存储配置（阿里云 OSS、腾讯云 COS、华为云 OBS、MinIO）
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint"`                 // 服务地址，如 https://oss-cn-hangzhou.aliyuncs.com
+	Region          string `yaml:"region,omitempty"`         // 签名使用的区域，默认 us-east-1
+	Bucket          string `yaml:"bucket"`                   // 存储桶
+	Prefix          string `yaml:"prefix,omitempty"`         // 对象名前缀
+	AccessKeyID     string `yaml:"access_key_id"`            // 访问密钥 ID
+	SecretAccessKey string `yaml:"secret_access_key"`        // 访问密钥
+	SessionToken    string `yaml:"session_token,omitempty"`  // 临时凭证的会话令牌
+	PathStyle       bool   `yaml:"path_style,omitempty"`     // 使用路径风格访问（MinIO 等需要开启）
+	SSE             string `yaml:"sse,omitempty"`            // 服务端加密: AES256, aws:kms
+	SSEKMSKeyID     string `yaml:"sse_kms_key_id,omitempty"` // KMS 加密使用的密钥 ID
+	Versioning      bool   `yaml:"versioning,omitempty"`     // 为存储桶开启版本控制，保留证书历史版本
+}
+
+// VaultConfig HashiCorp Vault KV v2 配置
+type VaultConfig struct {
+	Address   string          `yaml:"address"`             // Vault 地址，如 https://vault.example.com:8200
+	Namespace string          `yaml:"namespace,omitempty"` // 命名空间（Vault 企业版）
+	Mount     string          `yaml:"mount,omitempty"`     // KV v2 挂载路径，默认 secret
+	Path      string          `yaml:"path,omitempty"`      // 证书路径模板，默认 ssl-manager/{{.Domain}}
+	CACert    string          `yaml:"ca_cert,omitempty"`   // 校验 Vault 服务端证书的 CA 文件
+	Auth      VaultAuthConfig `yaml:"auth"`                // 认证配置
+}
+
+// VaultAuthConfig Vault 认证配置
+type VaultAuthConfig struct {
+	Method    string `yaml:"method,omitempty"`     // 认证方式: token, approle, kubernetes，默认 token
+	MountPath string `yaml:"mount_path,omitempty"` // 认证方式的挂载路径，默认与认证方式同名
+	Token     string `yaml:"token,omitempty"`      // token 认证，为空时读取 VAULT_TOKEN 环境变量
+	RoleID    string `yaml:"role_id,omitempty"`    // approle 认证的 role_id
+	SecretID  string `yaml:"secret_id,omitempty"`  // approle 认证的 secret_id
+	Role      string `yaml:"role,omitempty"`       // kubernetes 认证的角色
+	JWTPath   string `yaml:"jwt_path,omitempty"`   // kubernetes ServiceAccount token 路径
+}
+
 // ProvidersConfig 云平台凭证配置
 type ProvidersConfig struct {
 	Aliyun  *AliyunConfig  `yaml:"aliyun,omitempty"`
@@ -63,6 +150,182 @@ type DomainConfig struct {
 
 	RenewDays   int    `yaml:"renew_days"`
 	PostCommand string `yaml:"post_command,omitempty"`
+
+	// 续期判断依据（为空时使用全局配置）:
+	//   local - 解析本地保存的 cert.pem
+	//   live  - 连接 域名:443 检查线上证书
+	//   both  - 优先检查本地证书，本地证书不可用时再检查线上证书
+	RenewSource string `yaml:"renew_source,omitempty"`
+
+	// 线上证书检查端点（为空时检查 域名:443）
+	Probes []ProbeConfig `yaml:"probes,omitempty"`
+
+	// 额外输出的证书格式（为空时只保存 PEM 格式的证书、私钥和证书链）
+	Formats *FormatsConfig `yaml:"formats,omitempty"`
+
+	// 证书文件布局、属主和权限（为空时保存到 <证书目录>/<域名>/ 下）
+	Layout *LayoutConfig `yaml:"layout,omitempty"`
+
+	// 部署目标，证书发生变化时依次部署（在后置命令之前执行）
+	Deploy []DeployConfig `yaml:"deploy,omitempty"`
+}
+
+// 部署目标类型
+const (
+	DeployCopy = "copy"
+	DeployExec = "exec"
+
+	// 阿里云部署目标：证书上传到阿里云数字证书管理服务 (CAS) 后绑定到云产品
+	DeployAliyunCDN  = "aliyun_cdn"
+	DeployAliyunDCDN = "aliyun_dcdn"
+	DeployAliyunSLB  = "aliyun_slb"
+	DeployAliyunALB  = "aliyun_alb"
+	DeployAliyunOSS  = "aliyun_oss"
+)
+
+// DeployConfig 部署目标配置
+type DeployConfig struct {
+	Name       string `yaml:"name,omitempty"`        // 目标名称，用于日志、状态库和通知，默认为 <类型>-<序号>
+	Type       string `yaml:"type"`                  // 目标类型: copy, exec, aliyun_cdn, aliyun_dcdn, aliyun_slb, aliyun_alb, aliyun_oss
+	Retries    *int   `yaml:"retries,omitempty"`     // 失败后的重试次数，默认 2
+	RetryDelay int    `yaml:"retry_delay,omitempty"` // 首次重试的等待时间（秒），之后每次翻倍，默认 10
+	Timeout    int    `yaml:"timeout,omitempty"`     // 单次部署的超时时间（秒），默认 300
+
+	Copy *LayoutConfig     `yaml:"copy,omitempty"` // copy: 复制证书文件到本地目录（如挂载的共享目录），字段与 layout 相同
+	Exec *ExecDeployConfig `yaml:"exec,omitempty"` // exec: 执行命令
+
+	Aliyun *AliyunDeployConfig `yaml:"aliyun,omitempty"` // aliyun_*: 阿里云云产品，凭证使用 providers.aliyun
+}
+
+// ExecDeployConfig 执行命令的部署目标配置
+type ExecDeployConfig struct {
+	Command string `yaml:"command"` // 通过 sh -c 执行的命令，支持与 post_command 相同的变量（同时以环境变量传入）
+}
+
+// AliyunDeployConfig 阿里云部署目标配置
+type AliyunDeployConfig struct {
+	Domain         string `yaml:"domain,omitempty"`           // aliyun_cdn/aliyun_dcdn/aliyun_oss: 加速域名或自定义域名，默认为证书域名
+	Region         string `yaml:"region,omitempty"`           // aliyun_slb/aliyun_alb/aliyun_oss: 实例所在地域，默认为 providers.aliyun.region，都未配置时为 cn-hangzhou
+	LoadBalancerID string `yaml:"load_balancer_id,omitempty"` // aliyun_slb: 负载均衡实例ID
+	ListenerPort   int    `yaml:"listener_port,omitempty"`    // aliyun_slb: HTTPS 监听端口，默认 443
+	ListenerID     string `yaml:"listener_id,omitempty"`      // aliyun_alb: HTTPS 监听ID
+	Bucket         string `yaml:"bucket,omitempty"`           // aliyun_oss: Bucket 名称
+	Endpoint       string `yaml:"endpoint,omitempty"`         // 云产品接入地址，默认按类型和地域选择，可带 http:// 前缀指向本地模拟服务
+	CASEndpoint    string `yaml:"cas_endpoint,omitempty"`     // 证书服务接入地址，默认 cas.aliyuncs.com
+	CASRegion      string `yaml:"cas_region,omitempty"`       // 证书服务所在地域，用于 ALB/OSS 引用证书，默认 cn-hangzhou（国际站为 ap-southeast-1）
+}
+
+// GetName 获取部署目标名称，index 为目标在域名 deploy 列表中的序号（从 0 开始）
+func (d *DeployConfig) GetName(index int) string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return fmt.Sprintf("%s-%d", d.Type, index+1)
+}
+
+// GetRetries 获取失败后的重试次数（未配置时为 2）
+func (d *DeployConfig) GetRetries() int {
+	if d.Retries == nil {
+		return 2
+	}
+	return *d.Retries
+}
+
+// LayoutConfig 证书文件布局配置
+// 配置 dir 或 files 时，证书文件会复制到对应位置（随版本切换和回滚同步更新）
+type LayoutConfig struct {
+	Dir     string            `yaml:"dir,omitempty"`      // 目录模板，如 /etc/nginx/ssl，默认为 <证书目录>/<域名>
+	Files   map[string]string `yaml:"files,omitempty"`    // 文件名模板，键为 cert.pem、key.pem 等，值为相对 dir 的文件名或绝对路径
+	Owner   string            `yaml:"owner,omitempty"`    // 文件属主（用户名或 UID）
+	Group   string            `yaml:"group,omitempty"`    // 文件属组（组名或 GID）
+	Mode    string            `yaml:"mode,omitempty"`     // 证书文件权限，默认 0644
+	KeyMode string            `yaml:"key_mode,omitempty"` // 含私钥文件的权限，默认 0600
+	DirMode string            `yaml:"dir_mode,omitempty"` // 新建目录的权限，默认 0755
+}
+
+// FormatsConfig 额外输出的证书格式配置
+type FormatsConfig struct {
+	PKCS12   *PKCS12Config `yaml:"pkcs12,omitempty"`   // PKCS#12 (cert.pfx)，IIS、Windows 等使用
+	JKS      *JKSConfig    `yaml:"jks,omitempty"`      // Java KeyStore (keystore.jks)，Tomcat 等使用
+	DER      bool          `yaml:"der,omitempty"`      // DER 编码的证书和私钥 (cert.der, key.der)
+	Combined bool          `yaml:"combined,omitempty"` // 证书链和私钥合并的 PEM (combined.pem)，HAProxy 使用
+	Chain    bool          `yaml:"chain,omitempty"`    // 只包含中间证书的证书链 (chain.pem)
+}
+
+// PKCS12Config PKCS#12 输出配置
+type PKCS12Config struct {
+	Password     string `yaml:"password,omitempty"`      // 密码
+	PasswordFile string `yaml:"password_file,omitempty"` // 从文件读取密码，避免密码写入配置文件
+	Legacy       bool   `yaml:"legacy,omitempty"`        // 使用旧版加密算法 (3DES)，兼容 Windows Server 2016、Java 8 等旧环境
+}
+
+// JKSConfig Java KeyStore 输出配置
+type JKSConfig struct {
+	Password     string `yaml:"password,omitempty"`      // 密钥库和私钥的密码
+	PasswordFile string `yaml:"password_file,omitempty"` // 从文件读取密码，避免密码写入配置文件
+	Alias        string `yaml:"alias,omitempty"`         // 私钥条目别名，默认为域名
+}
+
+// ProbeConfig 线上证书检查端点配置
+type ProbeConfig struct {
+	Host       string   `yaml:"host,omitempty"`        // 连接地址，默认为域名
+	Port       int      `yaml:"port,omitempty"`        // 端口，默认 443（STARTTLS 时为协议默认端口）
+	SNI        string   `yaml:"sni,omitempty"`         // TLS SNI，默认为域名
+	IPs        []string `yaml:"ips,omitempty"`         // 指定检查的 IP 地址
+	ResolveAll bool     `yaml:"resolve_all,omitempty"` // 解析 host 的所有 A/AAAA 记录并逐个检查
+	Timeout    int      `yaml:"timeout,omitempty"`     // 连接超时（秒），默认 10
+	Proxy      string   `yaml:"proxy,omitempty"`       // 代理地址: http://host:port 或 socks5://host:port
+	StartTLS   string   `yaml:"starttls,omitempty"`    // STARTTLS 协议: smtp, imap, pop3, postgres
+}
+
+// GetModes 获取证书文件、含私钥文件和目录的权限（未配置时分别为 0644、0600、0755）
+func (l *LayoutConfig) GetModes() (mode, keyMode, dirMode os.FileMode, err error) {
+	if mode, err = parseFileMode(l.Mode, 0644); err != nil {
+		return 0, 0, 0, fmt.Errorf("layout.mode: %w", err)
+	}
+	if keyMode, err = parseFileMode(l.KeyMode, 0600); err != nil {
+		return 0, 0, 0, fmt.Errorf("layout.key_mode: %w", err)
+	}
+	if dirMode, err = parseFileMode(l.DirMode, 0755); err != nil {
+		return 0, 0, 0, fmt.Errorf("layout.dir_mode: %w", err)
+	}
+	return mode, keyMode, dirMode, nil
+}
+
+// GetOwnership 获取文件属主和属组的 UID/GID（未配置时为 -1，表示不修改）
+func (l *LayoutConfig) GetOwnership() (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if l.Owner != "" {
+		if uid, err = strconv.Atoi(l.Owner); err != nil {
+			u, err := user.Lookup(l.Owner)
+			if err != nil {
+				return -1, -1, fmt.Errorf("layout.owner: 查找用户 %s 失败: %w", l.Owner, err)
+			}
+			uid, _ = strconv.Atoi(u.Uid)
+		}
+	}
+	if l.Group != "" {
+		if gid, err = strconv.Atoi(l.Group); err != nil {
+			g, err := user.LookupGroup(l.Group)
+			if err != nil {
+				return -1, -1, fmt.Errorf("layout.group: 查找用户组 %s 失败: %w", l.Group, err)
+			}
+			gid, _ = strconv.Atoi(g.Gid)
+		}
+	}
+	return uid, gid, nil
+}
+
+// parseFileMode 解析八进制的文件权限，如 0640，为空时返回默认值
+func parseFileMode(value string, def os.FileMode) (os.FileMode, error) {
+	if value == "" {
+		return def, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("无效的文件权限: %s", value)
+	}
+	return os.FileMode(mode), nil
 }
 
 // GetCertProvider 获取证书提供商名称
@@ -87,6 +350,55 @@ func (d *DomainConfig) GetDNSProvider() string {
 	return "aliyun" // 默认使用阿里云
 }
 
+// FindDomain 查找域名配置，未找到时返回 nil
+func (c *Config) FindDomain(domain string) *DomainConfig {
+	for i := range c.Domains {
+		if c.Domains[i].Domain == domain {
+			return &c.Domains[i]
+		}
+	}
+	return nil
+}
+
+// CertProviderNames 返回域名配置中使用的证书提供商名称（去重，保持配置顺序）
+func (c *Config) CertProviderNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for i := range c.Domains {
+		name := c.Domains[i].GetCertProvider()
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// 续期判断依据
+const (
+	RenewSourceLocal = "local"
+	RenewSourceLive  = "live"
+	RenewSourceBoth  = "both"
+)
+
+// GetRenewSource 获取域名的续期判断依据
+func (c *Config) GetRenewSource(d *DomainConfig) string {
+	if d.RenewSource != "" {
+		return d.RenewSource
+	}
+	if c.RenewSource != "" {
+		return c.RenewSource
+	}
+	return RenewSourceLive
+}
+
+// OCSPConfig OCSP 状态检查配置
+// 开启后定期查询证书的 OCSP 状态，保存 ocsp.der 供 OCSP Stapling 使用，证书被吊销时立即重新签发
+type OCSPConfig struct {
+	Enabled  bool `yaml:"enabled"`            // 是否启用
+	Interval int  `yaml:"interval,omitempty"` // 守护进程中刷新 OCSP 响应的间隔（小时），默认12
+}
+
 // WebhookConfig Webhook 通知配置
 type WebhookConfig struct {
 	Enabled bool              `yaml:"enabled"` // 是否启用
@@ -2,7 +2,12 @@ package config
 
 import (
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"strings"
+	"text/template"
 
 	"gopkg.in/yaml.v3"
 )
@@ -28,12 +33,30 @@ func Load(path string) (*Config, error) {
 	if config.OutputDir == "" {
 		config.OutputDir = "./certs"
 	}
+	if config.Storage.Type == "" {
+		config.Storage.Type = StorageFile
+	}
+	if config.Storage.Path == "" {
+		config.Storage.Path = config.OutputDir
+	}
+	if config.Storage.Retention <= 0 {
+		config.Storage.Retention = 5
+	}
+	if config.Storage.Encryption != nil && config.Storage.Encryption.DecryptDir == "" {
+		config.Storage.Encryption.DecryptDir = "/dev/shm/ssl-manager"
+	}
 	if config.CheckInterval == 0 {
 		config.CheckInterval = 24
 	}
+	if config.OCSP != nil && config.OCSP.Interval <= 0 {
+		config.OCSP.Interval = 12
+	}
 	if config.Concurrency <= 0 {
 		config.Concurrency = 1 // 默认并发数为1，保持向后兼容
 	}
+	if config.DriftGrace <= 0 {
+		config.DriftGrace = 24
+	}
 
 	// 验证配置
 	if err := validate(&config); err != nil {
@@ -49,6 +72,10 @@ func validate(config *Config) error {
 		return fmt.Errorf("未配置任何域名")
 	}
 
+	if err := validateStorage(&config.Storage); err != nil {
+		return err
+	}
+
 	// 检查每个域名配置的提供商凭证是否存在
 	for _, domain := range config.Domains {
 		certProvider := domain.GetCertProvider()
@@ -65,11 +92,305 @@ func validate(config *Config) error {
 		if domain.RenewDays <= 0 {
 			return fmt.Errorf("域名 %s: renew_days 必须大于 0", domain.Domain)
 		}
+
+		if err := validateRenewSource(config.GetRenewSource(&domain)); err != nil {
+			return fmt.Errorf("域名 %s: %w", domain.Domain, err)
+		}
+
+		for _, probe := range domain.Probes {
+			if err := validateProbe(&probe); err != nil {
+				return fmt.Errorf("域名 %s: %w", domain.Domain, err)
+			}
+		}
+
+		if domain.Formats != nil {
+			if err := validateFormats(domain.Formats, config.Storage.Type); err != nil {
+				return fmt.Errorf("域名 %s: %w", domain.Domain, err)
+			}
+		}
+
+		if domain.Layout != nil {
+			if err := validateLayout(domain.Layout, domain.Domain); err != nil {
+				return fmt.Errorf("域名 %s: %w", domain.Domain, err)
+			}
+		}
+
+		names := map[string]bool{}
+		for i := range domain.Deploy {
+			target := &domain.Deploy[i]
+			name := target.GetName(i)
+			if names[name] {
+				return fmt.Errorf("域名 %s: 部署目标名称重复: %s", domain.Domain, name)
+			}
+			names[name] = true
+			if err := validateDeploy(target, domain.Domain, config.Providers.Aliyun); err != nil {
+				return fmt.Errorf("域名 %s: 部署目标 %s: %w", domain.Domain, name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateStorage 验证存储配置
+func validateStorage(storage *StorageConfig) error {
+	if storage.Encryption != nil {
+		if storage.Type == StorageVault {
+			return fmt.Errorf("vault 存储不在本地保存私钥，不支持 storage.encryption")
+		}
+		if err := validateEncryption(storage.Encryption); err != nil {
+			return err
+		}
+	}
+
+	switch storage.Type {
+	case StorageFile:
+		return nil
+	case StorageS3:
+		s3 := storage.S3
+		if s3 == nil {
+			return fmt.Errorf("存储后端 s3 未配置 storage.s3")
+		}
+		if s3.Endpoint == "" || s3.Bucket == "" {
+			return fmt.Errorf("storage.s3 需要配置 endpoint 和 bucket")
+		}
+		if s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+			return fmt.Errorf("storage.s3 凭证不完整")
+		}
+		switch s3.SSE {
+		case "", "AES256", "aws:kms":
+		default:
+			return fmt.Errorf("不支持的服务端加密方式: %s (可选: AES256, aws:kms)", s3.SSE)
+		}
+		return nil
+	case StorageVault:
+		vault := storage.Vault
+		if vault == nil {
+			return fmt.Errorf("存储后端 vault 未配置 storage.vault")
+		}
+		if vault.Address == "" {
+			return fmt.Errorf("storage.vault 需要配置 address")
+		}
+		switch vault.Auth.Method {
+		case "", "token":
+		case "approle":
+			if vault.Auth.RoleID == "" || vault.Auth.SecretID == "" {
+				return fmt.Errorf("storage.vault approle 认证需要配置 role_id 和 secret_id")
+			}
+		case "kubernetes":
+			if vault.Auth.Role == "" {
+				return fmt.Errorf("storage.vault kubernetes 认证需要配置 role")
+			}
+		default:
+			return fmt.Errorf("不支持的 Vault 认证方式: %s (可选: token, approle, kubernetes)", vault.Auth.Method)
+		}
+		return nil
+	default:
+		return fmt.Errorf("不支持的存储后端: %s (可选: file, s3, vault)", storage.Type)
+	}
+}
+
+// validateEncryption 验证私钥加密配置
+func validateEncryption(encryption *EncryptionConfig) error {
+	switch encryption.Type {
+	case EncryptionPKCS8:
+		if encryption.PassphraseEnv == "" && encryption.PassphraseFile == "" {
+			return fmt.Errorf("storage.encryption pkcs8 需要配置 passphrase_env 或 passphrase_file")
+		}
+	case EncryptionAge:
+		if len(encryption.Recipients) == 0 || encryption.IdentityFile == "" {
+			return fmt.Errorf("storage.encryption age 需要配置 recipients 和 identity_file")
+		}
+	default:
+		return fmt.Errorf("不支持的私钥加密方式: %s (可选: pkcs8, age)", encryption.Type)
+	}
+	return nil
+}
+
+// validateRenewSource 验证续期判断依据
+func validateRenewSource(source string) error {
+	switch source {
+	case RenewSourceLocal, RenewSourceLive, RenewSourceBoth:
+		return nil
+	default:
+		return fmt.Errorf("不支持的 renew_source: %s (可选: local, live, both)", source)
+	}
+}
+
+// validateProbe 验证线上证书检查端点配置
+func validateProbe(probe *ProbeConfig) error {
+	switch probe.StartTLS {
+	case "", "smtp", "imap", "pop3", "postgres":
+	default:
+		return fmt.Errorf("不支持的 starttls 协议: %s (可选: smtp, imap, pop3, postgres)", probe.StartTLS)
+	}
+	if probe.Port < 0 || probe.Port > 65535 {
+		return fmt.Errorf("无效的探测端口: %d", probe.Port)
+	}
+	for _, ip := range probe.IPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("无效的探测 IP: %s", ip)
+		}
+	}
+	if probe.Proxy != "" {
+		u, err := url.Parse(probe.Proxy)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("无效的代理地址: %s", probe.Proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("不支持的代理协议: %s (可选: http, https, socks5)", u.Scheme)
+		}
+	}
+	return nil
+}
+
+// validateFormats 验证额外输出格式配置，并读取 password_file 中的密码
+func validateFormats(formats *FormatsConfig, storageType string) error {
+	if storageType == StorageVault && (formats.PKCS12 != nil || formats.JKS != nil || formats.Combined) {
+		return fmt.Errorf("vault 存储不在本地保存私钥，不支持 pkcs12、jks 和 combined 格式")
+	}
+
+	if formats.PKCS12 != nil {
+		password, err := readPassword(formats.PKCS12.Password, formats.PKCS12.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("formats.pkcs12: %w", err)
+		}
+		formats.PKCS12.Password = password
+	}
+
+	if formats.JKS != nil {
+		password, err := readPassword(formats.JKS.Password, formats.JKS.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("formats.jks: %w", err)
+		}
+		if password == "" {
+			return fmt.Errorf("formats.jks 需要配置 password 或 password_file")
+		}
+		formats.JKS.Password = password
+	}
+	return nil
+}
+
+// readPassword 返回配置的密码，配置了密码文件时从文件读取（去掉末尾换行）
+func readPassword(password, passwordFile string) (string, error) {
+	if passwordFile == "" {
+		return password, nil
+	}
+	if password != "" {
+		return "", fmt.Errorf("password 和 password_file 只能配置一个")
+	}
+	data, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return "", fmt.Errorf("读取密码文件失败: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// layoutFileNames layout.files 支持的文件（与存储后端保存的文件名一致）
+var layoutFileNames = map[string]bool{
+	"cert.pem": true, "key.pem": true, "fullchain.pem": true,
+	"cert.pfx": true, "keystore.jks": true, "cert.der": true, "key.der": true,
+	"combined.pem": true, "chain.pem": true, "ocsp.der": true, "meta.json": true,
+}
+
+// validateLayout 验证证书文件布局配置
+func validateLayout(layout *LayoutConfig, domain string) error {
+	if err := validateLayoutTemplate(layout.Dir, domain); err != nil {
+		return fmt.Errorf("layout.dir 模板无效: %w", err)
+	}
+	for name, value := range layout.Files {
+		if !layoutFileNames[name] {
+			return fmt.Errorf("layout.files 不支持的文件: %s", name)
+		}
+		if value == "" {
+			return fmt.Errorf("layout.files.%s 不能为空", name)
+		}
+		if err := validateLayoutTemplate(value, domain); err != nil {
+			return fmt.Errorf("layout.files.%s 模板无效: %w", name, err)
+		}
 	}
 
+	if _, _, _, err := layout.GetModes(); err != nil {
+		return err
+	}
+	if _, _, err := layout.GetOwnership(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateDeploy 验证部署目标配置，aliyun 为阿里云凭证（阿里云部署目标使用）
+func validateDeploy(target *DeployConfig, domain string, aliyun *AliyunConfig) error {
+	if target.GetRetries() < 0 || target.RetryDelay < 0 || target.Timeout < 0 {
+		return fmt.Errorf("retries、retry_delay 和 timeout 不能为负数")
+	}
+
+	switch target.Type {
+	case DeployCopy:
+		if target.Copy == nil || (target.Copy.Dir == "" && len(target.Copy.Files) == 0) {
+			return fmt.Errorf("copy 类型需要配置 copy.dir 或 copy.files")
+		}
+		return validateLayout(target.Copy, domain)
+	case DeployExec:
+		if target.Exec == nil || target.Exec.Command == "" {
+			return fmt.Errorf("exec 类型需要配置 exec.command")
+		}
+	case DeployAliyunCDN, DeployAliyunDCDN, DeployAliyunSLB, DeployAliyunALB, DeployAliyunOSS:
+		return validateAliyunDeploy(target, aliyun)
+	case "":
+		return fmt.Errorf("未配置 type")
+	default:
+		return fmt.Errorf("不支持的部署目标类型: %s", target.Type)
+	}
+	return nil
+}
+
+// validateAliyunDeploy 验证阿里云部署目标配置
+func validateAliyunDeploy(target *DeployConfig, aliyun *AliyunConfig) error {
+	if aliyun == nil || aliyun.AccessKeyID == "" || aliyun.AccessKeySecret == "" {
+		return fmt.Errorf("%s 类型需要配置 providers.aliyun 凭证", target.Type)
+	}
+	cfg := target.Aliyun
+	if cfg == nil {
+		cfg = &AliyunDeployConfig{}
+	}
+
+	switch target.Type {
+	case DeployAliyunSLB:
+		if cfg.LoadBalancerID == "" {
+			return fmt.Errorf("aliyun_slb 类型需要配置 aliyun.load_balancer_id")
+		}
+		if cfg.ListenerPort < 0 || cfg.ListenerPort > 65535 {
+			return fmt.Errorf("无效的监听端口: %d", cfg.ListenerPort)
+		}
+	case DeployAliyunALB:
+		if cfg.ListenerID == "" {
+			return fmt.Errorf("aliyun_alb 类型需要配置 aliyun.listener_id")
+		}
+	case DeployAliyunOSS:
+		if cfg.Bucket == "" {
+			return fmt.Errorf("aliyun_oss 类型需要配置 aliyun.bucket")
+		}
+	}
+	for _, endpoint := range []string{cfg.Endpoint, cfg.CASEndpoint} {
+		if strings.Contains(endpoint, "://") && !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+			return fmt.Errorf("无效的接入地址: %s", endpoint)
+		}
+	}
 	return nil
 }
 
+// validateLayoutTemplate 验证目录或文件名模板能否渲染（支持的变量: {{.Domain}}）
+func validateLayoutTemplate(text, domain string) error {
+	tmpl, err := template.New("layout").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(io.Discard, map[string]string{"Domain": domain})
+}
+
 // validateProviderConfig 验证提供商配置是否存在
 func validateProviderConfig(config *Config, providerName, providerType string) error {
 	switch providerName {
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/deploy"
+	"ssl-manager/internal/state"
+	"ssl-manager/internal/storage"
+)
+
+// errCertRevoked 当前保存的证书已被吊销，拒绝部署
+var errCertRevoked = errors.New("证书已被吊销，拒绝部署")
+
+// newDeployers 为配置了部署目标的域名创建部署目标列表
+func newDeployers(domains []config.DomainConfig, providers *config.ProvidersConfig) (map[string]*deploy.Deployer, error) {
+	deployers := map[string]*deploy.Deployer{}
+	for _, domainCfg := range domains {
+		if len(domainCfg.Deploy) == 0 {
+			continue
+		}
+		deployer, err := deploy.New(domainCfg.Domain, domainCfg.Deploy, providers)
+		if err != nil {
+			return nil, fmt.Errorf("域名 %s: %w", domainCfg.Domain, err)
+		}
+		deployers[domainCfg.Domain] = deployer
+	}
+	return deployers, nil
+}
+
+// deployCertificate 证书发生变化后部署：依次部署到域名配置的部署目标，然后执行后置命令
+// 证书已被吊销时不部署，也不执行后置命令
+func (m *Manager) deployCertificate(ctx context.Context, domainCfg config.DomainConfig, result *storage.SaveResult) {
+	if m.state.IsRevoked(domainCfg.Domain, result.Serial) {
+		log.Printf("部署证书失败: %v (序列号: %s)，跳过后置命令", errCertRevoked, result.Serial)
+		return
+	}
+	if _, err := m.deployTargets(ctx, domainCfg.Domain, result); err != nil {
+		log.Printf("部署证书失败: %v", err)
+	}
+	m.runPostCommand(domainCfg, result)
+}
+
+// retryDeployments 证书未变化时补充部署：上次部署失败、尚未部署当前证书或新增的部署目标
+func (m *Manager) retryDeployments(ctx context.Context, domain string) {
+	deployer := m.deployers[domain]
+	if deployer == nil {
+		return
+	}
+	meta, err := m.Metadata(domain)
+	if err != nil {
+		return
+	}
+
+	var pending []string
+	for _, name := range deployer.Names() {
+		record := m.state.Deployment(domain, name)
+		if record == nil || record.Serial != meta.Serial || record.Status != state.DeploySucceeded {
+			pending = append(pending, name)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	log.Printf("补充部署当前证书到: %s", strings.Join(pending, ", "))
+	result := &storage.SaveResult{Serial: meta.Serial, Metadata: *meta}
+	if _, err := m.deployTargets(ctx, domain, result, pending...); err != nil {
+		log.Printf("部署证书失败: %v", err)
+	}
+}
+
+// Deploy 将域名当前保存的证书重新部署到部署目标（不执行后置命令），target 为空时部署到所有目标
+func (m *Manager) Deploy(ctx context.Context, domain, target string) ([]deploy.Result, error) {
+	if m.config.FindDomain(domain) == nil {
+		return nil, fmt.Errorf("域名 %s 不在配置中", domain)
+	}
+	deployer := m.deployers[domain]
+	if deployer == nil {
+		return nil, fmt.Errorf("域名 %s 没有配置部署目标", domain)
+	}
+
+	var names []string
+	if target != "" {
+		found := false
+		for _, name := range deployer.Names() {
+			found = found || name == target
+		}
+		if !found {
+			return nil, fmt.Errorf("域名 %s 没有名为 %s 的部署目标，可用的目标: %s", domain, target, strings.Join(deployer.Names(), ", "))
+		}
+		names = []string{target}
+	}
+
+	meta, err := m.Metadata(domain)
+	if err != nil {
+		return nil, fmt.Errorf("读取证书失败: %w", err)
+	}
+	result := &storage.SaveResult{Serial: meta.Serial, Metadata: *meta}
+	return m.deployTargets(ctx, domain, result, names...)
+}
+
+// deployTargets 将当前保存的证书部署到域名的部署目标，names 不为空时只部署指定目标
+// 每个目标的结果记录到状态库，失败时发送 deploy_failed 通知
+func (m *Manager) deployTargets(ctx context.Context, domain string, result *storage.SaveResult, names ...string) ([]deploy.Result, error) {
+	deployer := m.deployers[domain]
+	if deployer == nil {
+		return nil, nil
+	}
+
+	// 重新读取已保存的证书：私钥已解密，证书链已补全
+	cert, err := m.storage.LoadCertificate(domain)
+	if err != nil {
+		return nil, fmt.Errorf("读取证书失败: %w", err)
+	}
+	if m.certRevoked(domain, cert) {
+		return nil, fmt.Errorf("%w (序列号: %s)", errCertRevoked, result.Serial)
+	}
+	paths := m.storage.Paths(domain)
+	req := &deploy.Request{
+		Domain:      domain,
+		Certificate: cert,
+		Paths:       paths,
+		Vars:        m.executor.BuildVars(domain, paths, result),
+	}
+
+	results := deployer.Deploy(ctx, req, names...)
+	for _, r := range results {
+		record := &state.DeploymentRecord{
+			Domain:   domain,
+			Target:   r.Target,
+			Type:     r.Type,
+			Serial:   result.Serial,
+			Status:   state.DeploySucceeded,
+			Attempts: r.Attempts,
+		}
+		if r.Err != nil {
+			record.Status = state.DeployFailed
+			record.Error = r.Err.Error()
+			record.NotifiedAt = m.notifyDeployFailed(ctx, domain, result.Serial, r)
+		}
+		m.logStateError(m.state.RecordDeployment(record))
+	}
+	return results, nil
+}
+
+// notifyDeployFailed 发送 deploy_failed 通知，返回通知时间（未通知时为零值）
+// 同一目标部署同一证书持续失败时只通知一次，部署成功或证书变化后再次失败时才重新通知
+func (m *Manager) notifyDeployFailed(ctx context.Context, domain, serial string, r deploy.Result) time.Time {
+	previous := m.state.Deployment(domain, r.Target)
+	if previous != nil && previous.Status == state.DeployFailed && previous.Serial == serial && !previous.NotifiedAt.IsZero() {
+		log.Printf("部署目标 %s 部署该证书失败已通知过，跳过 deploy_failed 通知", r.Target)
+		return previous.NotifiedAt
+	}
+	if m.notifier == nil {
+		return time.Time{}
+	}
+	if err := m.notifier.NotifyDeployFailed(ctx, domain, r.Target, r.Type, serial, r.Attempts, r.Err.Error()); err != nil {
+		log.Printf("发送 deploy_failed 通知失败: %v", err)
+		return time.Time{}
+	}
+	return time.Now()
+}
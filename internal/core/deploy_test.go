@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/notification"
+	"ssl-manager/internal/state"
+)
+
+func TestDeployFailedNotifiedOncePerSerial(t *testing.T) {
+	ca := newTestCA(t)
+	retries := 0
+	domains := []config.DomainConfig{{
+		Domain:    "www.example.com",
+		Provider:  "fake",
+		RenewDays: 7,
+		Deploy: []config.DeployConfig{{
+			Name:    "broken",
+			Type:    config.DeployExec,
+			Retries: &retries,
+			Exec:    &config.ExecDeployConfig{Command: "exit 1"},
+		}},
+	}}
+	m, certProvider := newTestManager(t, ca, domains)
+
+	webhook := &eventWebhook{event: notification.EventDeployFailed}
+	hook := httptest.NewServer(webhook)
+	defer hook.Close()
+	m.notifier = notification.NewWebhookNotifier(&config.WebhookConfig{Enabled: true, URL: hook.URL})
+	ctx := context.Background()
+
+	certProvider.certs["cert-1"] = ca.issue(t, "www.example.com")
+	if err := m.ContinueOrder(ctx, "cert-1", "www.example.com", "fake", "fake"); err != nil {
+		t.Fatal(err)
+	}
+	if got := webhook.count(); got != 1 {
+		t.Fatalf("deploy_failed 通知次数 = %d, 期望 1", got)
+	}
+
+	// 每次检查都会补充部署并再次失败，同一证书不重复通知
+	m.retryDeployments(ctx, "www.example.com")
+	m.retryDeployments(ctx, "www.example.com")
+	if got := webhook.count(); got != 1 {
+		t.Fatalf("重复部署失败后 deploy_failed 通知次数 = %d, 期望 1", got)
+	}
+	record := m.state.Deployment("www.example.com", "broken")
+	if record == nil || record.Status != state.DeployFailed || record.NotifiedAt.IsZero() {
+		t.Fatalf("部署记录 = %+v", record)
+	}
+
+	// 证书变化后再次失败时重新通知
+	certProvider.certs["cert-2"] = ca.issue(t, "www.example.com")
+	if err := m.ContinueOrder(ctx, "cert-2", "www.example.com", "fake", "fake"); err != nil {
+		t.Fatal(err)
+	}
+	if got := webhook.count(); got != 2 {
+		t.Fatalf("证书变化后 deploy_failed 通知次数 = %d, 期望 2", got)
+	}
+}
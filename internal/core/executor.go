@@ -5,7 +5,11 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
+
+	"ssl-manager/internal/storage"
 )
 
 // Executor 命令执行器
@@ -42,12 +46,38 @@ func (e *Executor) RunPostCommand(command string, vars map[string]string) error
 }
 
 // BuildVars 构建变量映射
-func (e *Executor) BuildVars(domain, certDir, certFile, keyFile, fullchainFile string) map[string]string {
-	return map[string]string{
+func (e *Executor) BuildVars(domain string, paths storage.Paths, result *storage.SaveResult) map[string]string {
+	vars := map[string]string{
 		"DOMAIN":         domain,
-		"CERT_DIR":       certDir,
-		"CERT_FILE":      certFile,
-		"KEY_FILE":       keyFile,
-		"FULLCHAIN_FILE": fullchainFile,
+		"CERT_DIR":       paths.Dir,
+		"CERT_FILE":      paths.Cert,
+		"KEY_FILE":       paths.Key,
+		"FULLCHAIN_FILE": paths.Fullchain,
+		"PKCS12_FILE":    paths.PKCS12,
+		"JKS_FILE":       paths.JKS,
+		"CERT_DER_FILE":  paths.CertDER,
+		"KEY_DER_FILE":   paths.KeyDER,
+		"COMBINED_FILE":  paths.Combined,
+		"CHAIN_FILE":     paths.Chain,
+		"OCSP_FILE":      paths.OCSP,
+		"META_FILE":      paths.Meta,
+	}
+	if result != nil {
+		vars["CHANGED"] = strconv.FormatBool(result.Changed)
+		vars["SERIAL"] = result.Serial
+		vars["PREVIOUS_SERIAL"] = result.PreviousSerial
+
+		meta := result.Metadata
+		vars["CERT_ID"] = meta.CertID
+		vars["ORDER_ID"] = meta.OrderID
+		vars["ISSUER"] = meta.Issuer
+		vars["SANS"] = strings.Join(meta.SANs, ",")
+		vars["KEY_TYPE"] = meta.KeyType
+		vars["FINGERPRINT"] = meta.Fingerprint
+		if !meta.NotAfter.IsZero() {
+			vars["NOT_BEFORE"] = meta.NotBefore.Format(time.RFC3339)
+			vars["NOT_AFTER"] = meta.NotAfter.Format(time.RFC3339)
+		}
 	}
+	return vars
 }
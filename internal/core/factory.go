@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"sync"
 
 	"ssl-manager/internal/config"
 	"ssl-manager/internal/provider"
@@ -14,7 +15,8 @@ import (
 type Factory struct {
 	config *config.Config
 
-	// 缓存已创建的提供商实例
+	// 缓存已创建的提供商实例（并发处理域名时共享，需加锁）
+	mu            sync.Mutex
 	certProviders map[string]provider.CertProvider
 	dnsProviders  map[string]provider.DNSProvider
 }
@@ -30,6 +32,9 @@ func NewFactory(cfg *config.Config) *Factory {
 
 // GetCertProvider 获取证书提供商
 func (f *Factory) GetCertProvider(name string) (provider.CertProvider, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	// 检查缓存
 	if p, ok := f.certProviders[name]; ok {
 		return p, nil
@@ -71,8 +76,21 @@ func (f *Factory) GetCertProvider(name string) (provider.CertProvider, error) {
 	return p, nil
 }
 
+// ResetCaches 清空所有已创建证书提供商的证书列表缓存
+func (f *Factory) ResetCaches() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, p := range f.certProviders {
+		p.ResetCache()
+	}
+}
+
 // GetDNSProvider 获取DNS提供商
 func (f *Factory) GetDNSProvider(name string) (provider.DNSProvider, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	// 检查缓存
 	if p, ok := f.dnsProviders[name]; ok {
 		return p, nil
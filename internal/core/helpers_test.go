@@ -0,0 +1,269 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/notification"
+	"ssl-manager/internal/provider"
+)
+
+// testCA 测试用 CA，签发的证书直接由根证书签名
+type testCA struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	path   string // 根证书 PEM 文件，用作 trust_bundle
+	serial int64
+}
+
+// newTestCA 创建测试 CA 并把根证书写入临时目录
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(30 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, path: path, serial: 100}
+}
+
+// issue 为域名签发 12 小时有效的证书，返回叶子证书、私钥和证书链（叶子证书）
+func (ca *testCA) issue(t *testing.T, domain string) *provider.Certificate {
+	t.Helper()
+	return ca.issueFor(t, domain, 12*time.Hour)
+}
+
+// issueFor 为域名签发有效期为 validity 的证书
+func (ca *testCA) issueFor(t *testing.T, domain string, validity time.Duration) *provider.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	return &provider.Certificate{
+		Certificate: certPEM,
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		Chain:       certPEM,
+	}
+}
+
+// fakeCertProvider 测试用证书提供商，订单立即签发，证书来自 certs
+type fakeCertProvider struct {
+	certs   map[string]*provider.Certificate // 订单ID或证书ID -> 证书
+	valid   *provider.CertificateInfo        // FindValidCertificate 返回的证书
+	revoked []string                         // 已吊销的证书ID
+}
+
+func newFakeCertProvider() *fakeCertProvider {
+	return &fakeCertProvider{certs: map[string]*provider.Certificate{}}
+}
+
+func (p *fakeCertProvider) Name() string { return "fake" }
+
+func (p *fakeCertProvider) ApplyCertificate(ctx context.Context, domain string) (string, error) {
+	return "", fmt.Errorf("不支持申请证书")
+}
+
+func (p *fakeCertProvider) ListPendingOrders(ctx context.Context, domain string) ([]*provider.OrderInfo, error) {
+	return nil, nil
+}
+
+func (p *fakeCertProvider) ListOrders(ctx context.Context) ([]*provider.OrderInfo, error) {
+	return nil, nil
+}
+
+func (p *fakeCertProvider) CancelOrder(ctx context.Context, orderID string) error { return nil }
+
+func (p *fakeCertProvider) GetCertificateStatus(ctx context.Context, orderID string) (*provider.CertificateStatus, error) {
+	if _, ok := p.certs[orderID]; !ok {
+		return nil, fmt.Errorf("%w: %s", provider.ErrOrderNotFound, orderID)
+	}
+	return &provider.CertificateStatus{OrderID: orderID, Status: "certificate"}, nil
+}
+
+func (p *fakeCertProvider) DownloadCertificate(ctx context.Context, orderID string) (*provider.Certificate, error) {
+	cert, ok := p.certs[orderID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", provider.ErrOrderNotFound, orderID)
+	}
+	copied := *cert
+	return &copied, nil
+}
+
+func (p *fakeCertProvider) ListCertificates(ctx context.Context) ([]*provider.CertificateInfo, error) {
+	return nil, nil
+}
+
+func (p *fakeCertProvider) ResetCache() {}
+
+func (p *fakeCertProvider) FindValidCertificate(ctx context.Context, domain string, minDays int) (*provider.CertificateInfo, error) {
+	return p.valid, nil
+}
+
+func (p *fakeCertProvider) GetCertificateDetail(ctx context.Context, certID string) (*provider.Certificate, error) {
+	return p.DownloadCertificate(ctx, certID)
+}
+
+func (p *fakeCertProvider) ListExpiredCertificates(ctx context.Context) ([]*provider.CertificateInfo, error) {
+	return nil, nil
+}
+
+func (p *fakeCertProvider) ListBoundResources(ctx context.Context, certID string) ([]string, error) {
+	return nil, nil
+}
+
+func (p *fakeCertProvider) DeleteCertificate(ctx context.Context, certID string) error { return nil }
+
+func (p *fakeCertProvider) RevokeCertificate(ctx context.Context, certID, reason string) ([]*provider.DNSRecord, error) {
+	p.revoked = append(p.revoked, certID)
+	return nil, nil
+}
+
+// fakeDNSProvider 测试用 DNS 提供商，不做任何操作
+type fakeDNSProvider struct{}
+
+func (fakeDNSProvider) Name() string { return "fake" }
+
+func (fakeDNSProvider) AddRecord(ctx context.Context, domain, rr, recordType, value string) error {
+	return nil
+}
+
+func (fakeDNSProvider) UpdateRecord(ctx context.Context, domain, recordID, rr, recordType, value string) error {
+	return nil
+}
+
+func (fakeDNSProvider) DeleteRecord(ctx context.Context, domain, recordID string) error { return nil }
+
+func (fakeDNSProvider) FindRecord(ctx context.Context, domain, rr, recordType string) (*provider.DNSRecord, error) {
+	return nil, nil
+}
+
+func (fakeDNSProvider) ListRecords(ctx context.Context, domain string) ([]*provider.DNSRecord, error) {
+	return nil, nil
+}
+
+// newTestManager 创建使用测试 CA 和 fake 提供商的管理器，domains 中的 provider 应为 fake
+// 部署目标写入的文件等由调用方在 domains 中配置
+func newTestManager(t *testing.T, ca *testCA, domains []config.DomainConfig) (*Manager, *fakeCertProvider) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{
+		OutputDir:   dir,
+		Storage:     config.StorageConfig{Type: config.StorageFile, Path: dir, Retention: 5},
+		TrustBundle: ca.path,
+		Concurrency: 1,
+		DriftGrace:  24,
+		Domains:     domains,
+	}
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certProvider := newFakeCertProvider()
+	m.factory.certProviders["fake"] = certProvider
+	m.factory.dnsProviders["fake"] = fakeDNSProvider{}
+	return m, certProvider
+}
+
+// execDeploy 返回把证书序列号追加到 marker 文件的 exec 部署目标
+func execDeploy(marker string) config.DeployConfig {
+	retries := 0
+	return config.DeployConfig{
+		Name:    "marker",
+		Type:    config.DeployExec,
+		Retries: &retries,
+		Exec:    &config.ExecDeployConfig{Command: "echo ${SERIAL} >> " + marker},
+	}
+}
+
+// readMarker 读取 marker 文件中记录的序列号（每行一个）
+func readMarker(t *testing.T, marker string) string {
+	t.Helper()
+	data, err := os.ReadFile(marker)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// eventWebhook 记录收到的指定类型的 Webhook 通知
+type eventWebhook struct {
+	event  notification.EventType
+	mu     sync.Mutex
+	events []notification.EventData
+}
+
+func (w *eventWebhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var event notification.EventData
+	if err := json.NewDecoder(r.Body).Decode(&event); err == nil && event.Event == string(w.event) {
+		w.mu.Lock()
+		w.events = append(w.events, event)
+		w.mu.Unlock()
+	}
+}
+
+func (w *eventWebhook) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.events)
+}
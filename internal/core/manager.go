@@ -2,42 +2,100 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
 	"sync"
 	"time"
 
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/chain"
 	"ssl-manager/internal/config"
+	"ssl-manager/internal/deploy"
 	"ssl-manager/internal/notification"
+	"ssl-manager/internal/ocsp"
 	"ssl-manager/internal/provider"
+	"ssl-manager/internal/state"
 	"ssl-manager/internal/storage"
 )
 
+// maxInFlightOrderAge 未完成订单的最长恢复时间，超过后标记为失败
+const maxInFlightOrderAge = 72 * time.Hour
+
 // Manager 证书管理器
 type Manager struct {
 	config    *config.Config
 	factory   *Factory
-	storage   *storage.FileStorage
+	storage   storage.Backend
 	validator *Validator
+	chain     *chain.Completer
+	ocsp      *ocsp.Client
 	executor  *Executor
 	notifier  *notification.WebhookNotifier
+	state     *state.Store
+	deployers map[string]*deploy.Deployer // 域名 -> 部署目标，未配置部署目标的域名不在其中
+
+	resumeOnce  sync.Once // 首次运行时恢复未完成的订单
+	publishOnce sync.Once // 首次运行时重新发布证书文件
 }
 
 // NewManager 创建管理器
 func NewManager(cfg *config.Config) (*Manager, error) {
+	store, err := state.Open(filepath.Join(cfg.OutputDir, "state.json"))
+	if err != nil {
+		return nil, fmt.Errorf("打开状态库失败: %w", err)
+	}
+
+	backend, err := storage.New(&cfg.Storage, cfg.Domains)
+	if err != nil {
+		return nil, fmt.Errorf("创建存储后端失败: %w", err)
+	}
+
+	roots, err := certutil.LoadCertPool(cfg.TrustBundle)
+	if err != nil {
+		return nil, fmt.Errorf("加载 trust_bundle 失败: %w", err)
+	}
+
+	deployers, err := newDeployers(cfg.Domains, &cfg.Providers)
+	if err != nil {
+		return nil, fmt.Errorf("创建部署目标失败: %w", err)
+	}
+
 	return &Manager{
 		config:    cfg,
 		factory:   NewFactory(cfg),
-		storage:   storage.NewFileStorage(cfg.OutputDir),
-		validator: NewValidator(),
+		storage:   backend,
+		validator: NewValidator(roots),
+		chain:     chain.NewCompleter(filepath.Join(cfg.OutputDir, "intermediates"), nil),
+		ocsp:      ocsp.NewClient(nil),
 		executor:  NewExecutor(),
 		notifier:  notification.NewWebhookNotifier(cfg.Webhook),
+		state:     store,
+		deployers: deployers,
 	}, nil
 }
 
 // Run 运行证书管理
 func (m *Manager) Run(ctx context.Context) error {
 	log.Println("========== 开始检查证书 ==========")
+
+	// 每次运行重新查询证书列表，同一次运行内的域名共享查询结果
+	m.factory.ResetCaches()
+
+	// 进程启动后的首次运行，先恢复上次退出时未完成的订单
+	m.resumeOnce.Do(func() {
+		m.ResumeInFlightOrders(ctx)
+	})
+
+	// 解密到 tmpfs 的私钥在重启后丢失，首次运行时重新发布
+	m.publishOnce.Do(m.PublishCertificates)
+
+	// 记录本次运行结果
+	run := m.state.StartRun()
+	defer func() {
+		m.logStateError(m.state.FinishRun(run))
+	}()
 	
 	concurrency := m.config.Concurrency
 	if concurrency <= 0 {
@@ -50,20 +108,22 @@ func (m *Manager) Run(ctx context.Context) error {
 	// 如果只有一个域名或并发数为1，使用串行处理
 	if totalDomains == 1 || concurrency == 1 {
 		for _, domainCfg := range m.config.Domains {
-			if err := m.ProcessDomain(ctx, domainCfg); err != nil {
+			err := m.ProcessDomain(ctx, domainCfg)
+			if err != nil {
 				log.Printf("处理域名 %s 失败: %v", domainCfg.Domain, err)
 			}
+			run.AddResult(domainCfg.Domain, err)
 		}
 		log.Println("========== 检查完成 ==========")
 		return nil
 	}
 	
 	// 并发处理
-	return m.runConcurrent(ctx, concurrency)
+	return m.runConcurrent(ctx, concurrency, run)
 }
 
 // runConcurrent 并发处理域名
-func (m *Manager) runConcurrent(ctx context.Context, concurrency int) error {
+func (m *Manager) runConcurrent(ctx context.Context, concurrency int, run *state.RunRecord) error {
 	// 创建任务通道
 	domainChan := make(chan config.DomainConfig, len(m.config.Domains))
 	
@@ -94,6 +154,7 @@ func (m *Manager) runConcurrent(ctx context.Context, concurrency int) error {
 				mu.Unlock()
 				
 				err := m.ProcessDomain(ctx, domainCfg)
+				run.AddResult(domain, err)
 				
 				mu.Lock()
 				if err != nil {
@@ -135,6 +196,7 @@ func (m *Manager) ProcessDomain(ctx context.Context, domainCfg config.DomainConf
 	}
 
 	var certDownloaded bool
+	var saveResult *storage.SaveResult
 
 	// 1. 检查云平台是否有已签发的有效证书
 	log.Printf("检查%s是否有已签发的有效证书...", certProviderName)
@@ -154,13 +216,28 @@ func (m *Manager) ProcessDomain(ctx context.Context, domainCfg config.DomainConf
 
 		// 下载已有证书
 		cert, err := certProvider.GetCertificateDetail(ctx, existingCert.CertID)
+		if err == nil {
+			// 详情接口不一定返回订单ID，使用查询结果补全
+			if cert.CertID == "" {
+				cert.CertID = existingCert.CertID
+			}
+			if cert.OrderID == "" {
+				cert.OrderID = existingCert.OrderID
+			}
+		}
 		if err != nil {
 			log.Printf("下载已有证书失败: %v，将尝试申请新证书", err)
+		} else if m.certRevoked(domain, cert) {
+			log.Printf("云平台返回的证书已被吊销，将申请新证书")
+		} else if cert, err = m.prepareCertificate(ctx, domain, cert); err != nil {
+			return err
 		} else {
-			if err := m.storage.SaveCertificate(domain, cert); err != nil {
+			if result, err := m.storage.SaveCertificate(domain, cert); err != nil {
 				log.Printf("保存证书失败: %v", err)
 			} else {
 				log.Printf("域名 %s 已有有效证书，已下载完成！", domain)
+				m.recordCertificate(domain, certProviderName, result)
+				saveResult = result
 				certDownloaded = true
 			}
 		}
@@ -170,18 +247,19 @@ func (m *Manager) ProcessDomain(ctx context.Context, domainCfg config.DomainConf
 
 	// 2. 如果没有下载到证书，检查是否需要申请新证书
 	if !certDownloaded {
-		needRenew, expiry, err := m.validator.NeedRenew(domain, renewDays)
+		needRenew, expiry, err := m.needRenew(ctx, &domainCfg)
 		if err != nil {
-			log.Printf("检查线上证书失败: %v", err)
+			log.Printf("检查证书失败: %v", err)
 		}
 
 		if !needRenew {
-			log.Printf("线上证书有效，无需续期")
+			log.Printf("证书有效，无需续期")
+			m.retryDeployments(ctx, domain)
 			return nil
 		}
 
 		if !expiry.IsZero() {
-			log.Printf("线上证书将在 %s 过期，需要续期", expiry.Format("2006-01-02"))
+			log.Printf("证书将在 %s 过期，需要续期", expiry.Format("2006-01-02"))
 			// 发送证书即将过期通知
 			if m.notifier != nil {
 				daysRemaining := int(time.Until(expiry).Hours() / 24)
@@ -189,77 +267,123 @@ func (m *Manager) ProcessDomain(ctx context.Context, domainCfg config.DomainConf
 			}
 		}
 
-		// 申请新证书
-		orderID, err := certProvider.ApplyCertificate(ctx, domain)
+		result, err := m.issueCertificate(ctx, certProvider, dnsProvider, domain)
 		if err != nil {
-			// 发送证书申请失败通知
-			if m.notifier != nil {
-				m.notifier.NotifyCertFailed(ctx, domain, err.Error())
-			}
-			return fmt.Errorf("申请证书失败: %w", err)
+			return err
 		}
+		saveResult = result
 
-		// 等待DNS验证并下载证书
-		if err := m.waitForDNSValidation(ctx, certProvider, dnsProvider, domain, orderID); err != nil {
-			// 检查是否是超时错误
-			if err.Error() == fmt.Sprintf("等待超时，请检查云平台控制台，订单ID: %s", orderID) {
-				if m.notifier != nil {
-					m.notifier.NotifyDNSValidationTimeout(ctx, domain, orderID)
+		certDownloaded = true
+	}
+
+	// 3. 证书发生变化时部署并执行后置命令（开启 OCSP 检查时先获取新证书的 OCSP 响应），未变化时补充部署之前失败的部署目标
+	if certDownloaded {
+		if saveResult.Changed {
+			if m.ocspEnabled() {
+				if _, _, err := m.updateOCSP(ctx, domain); err != nil {
+					log.Printf("获取 OCSP 响应失败: %v", err)
 				}
 			}
-			return fmt.Errorf("域名验证失败: %w", err)
+			m.deployCertificate(ctx, domainCfg, saveResult)
+		} else {
+			log.Printf("证书未变化，跳过后置命令")
+			m.retryDeployments(ctx, domain)
 		}
+	}
 
-		// 下载证书
-		cert, err := certProvider.DownloadCertificate(ctx, orderID)
-		if err != nil {
-			// 发送证书申请失败通知
-			if m.notifier != nil {
-				m.notifier.NotifyCertFailed(ctx, domain, fmt.Sprintf("下载证书失败: %v", err))
-			}
-			return fmt.Errorf("下载证书失败: %w", err)
-		}
+	log.Printf("域名 %s 的证书处理完成！", domain)
+	return nil
+}
 
-		if err := m.storage.SaveCertificate(domain, cert); err != nil {
-			// 发送证书申请失败通知
-			if m.notifier != nil {
-				m.notifier.NotifyCertFailed(ctx, domain, fmt.Sprintf("保存证书失败: %v", err))
+// needRenew 按域名配置的续期判断依据检查是否需要续期
+func (m *Manager) needRenew(ctx context.Context, domainCfg *config.DomainConfig) (bool, time.Time, error) {
+	domain := domainCfg.Domain
+	source := m.config.GetRenewSource(domainCfg)
+
+	// OCSP 检查发现本地证书已被吊销，但重新签发失败
+	if cert, err := m.storage.LoadCertificate(domain); err == nil && m.certRevoked(domain, cert) {
+		log.Printf("本地证书已被吊销，需要重新申请")
+		return true, time.Time{}, nil
+	}
+
+	if source == config.RenewSourceLocal || source == config.RenewSourceBoth {
+		cert, err := m.storage.LoadCertificate(domain)
+		if err == nil {
+			needRenew, expiry, err := m.validator.NeedRenewLocal(cert, domain, domainCfg.RenewDays)
+			if err == nil || source == config.RenewSourceLocal {
+				return needRenew, expiry, err
 			}
-			return fmt.Errorf("保存证书失败: %w", err)
 		}
 
-		// 发送证书申请成功通知
-		if m.notifier != nil {
-			// 使用 orderID 作为 certID（因为 Certificate 结构体没有 CertID 字段）
-			m.notifier.NotifyCertRenewed(ctx, domain, orderID)
+		if source == config.RenewSourceLocal {
+			log.Printf("本地证书不可用: %v，需要申请新证书", err)
+			return true, time.Time{}, nil
 		}
+		log.Printf("本地证书不可用，改为检查线上证书")
+	}
 
-		certDownloaded = true
+	needRenew, expiry, err := m.validator.NeedRenew(ctx, domain, domainCfg.RenewDays, domainCfg.Probes)
+	if !needRenew || source != config.RenewSourceLive {
+		return needRenew, expiry, err
 	}
 
-	// 3. 执行后置命令
-	if certDownloaded {
-		postCommand := domainCfg.PostCommand
-		if postCommand == "" {
-			postCommand = m.config.PostCommand
-		}
+	// 本地证书仍然有效时，线上证书异常说明是部署问题，重新申请无法解决
+	cert, loadErr := m.storage.LoadCertificate(domain)
+	if loadErr != nil {
+		return needRenew, expiry, err
+	}
+	localRenew, localExpiry, localErr := m.validator.NeedRenewLocal(cert, domain, domainCfg.RenewDays)
+	if localErr != nil || localRenew {
+		return needRenew, expiry, err
+	}
+	log.Printf("本地证书仍然有效 (%s 过期)，线上端点未使用该证书，疑似部署不一致，跳过续期 (可使用 verify 命令查看详情)",
+		localExpiry.Format("2006-01-02"))
+	for _, result := range m.verifyDomain(ctx, domainCfg) {
+		m.reportDrift(ctx, result)
+	}
+	return false, localExpiry, nil
+}
 
-		if postCommand != "" {
-			vars := m.executor.BuildVars(
-				domain,
-				m.storage.GetCertDir(domain),
-				m.storage.GetCertPath(domain),
-				m.storage.GetKeyPath(domain),
-				m.storage.GetFullchainPath(domain),
-			)
-			if err := m.executor.RunPostCommand(postCommand, vars); err != nil {
-				log.Printf("执行后置命令失败: %v", err)
+// issueCertificate 为域名签发新证书：优先恢复进行中的订单，否则申请新证书，完成后保存
+func (m *Manager) issueCertificate(ctx context.Context, certProvider provider.CertProvider, dnsProvider provider.DNSProvider, domain string) (*storage.SaveResult, error) {
+	// 优先恢复云平台上进行中的订单，避免重复下单消耗免费额度
+	orderID := m.findPendingOrder(ctx, certProvider, domain)
+	if orderID != "" {
+		m.logStateError(m.state.RecordOrder(certProvider.Name(), dnsProvider.Name(), domain, orderID, "domain_verify"))
+	} else {
+		// 申请新证书
+		var err error
+		orderID, err = certProvider.ApplyCertificate(ctx, domain)
+		if err != nil {
+			// 发送证书申请失败通知
+			if m.notifier != nil {
+				m.notifier.NotifyCertFailed(ctx, domain, err.Error())
 			}
+			return nil, fmt.Errorf("申请证书失败: %w", err)
 		}
+		m.logStateError(m.state.RecordOrder(certProvider.Name(), dnsProvider.Name(), domain, orderID, "pending"))
 	}
 
-	log.Printf("域名 %s 的证书处理完成！", domain)
-	return nil
+	// 等待DNS验证并下载证书
+	return m.completeOrder(ctx, certProvider, dnsProvider, domain, orderID)
+}
+
+// runPostCommand 执行域名的后置命令（域名级别优先于全局配置）
+func (m *Manager) runPostCommand(domainCfg config.DomainConfig, result *storage.SaveResult) {
+	postCommand := domainCfg.PostCommand
+	if postCommand == "" {
+		postCommand = m.config.PostCommand
+	}
+
+	if postCommand == "" {
+		return
+	}
+
+	domain := domainCfg.Domain
+	vars := m.executor.BuildVars(domain, m.storage.Paths(domain), result)
+	if err := m.executor.RunPostCommand(postCommand, vars); err != nil {
+		log.Printf("执行后置命令失败: %v", err)
+	}
 }
 
 // waitForDNSValidation 等待DNS验证完成
@@ -281,23 +405,28 @@ func (m *Manager) waitForDNSValidation(ctx context.Context, certProvider provide
 
 		status, err := certProvider.GetCertificateStatus(ctx, orderID)
 		if err != nil {
+			if errors.Is(err, provider.ErrOrderNotFound) {
+				m.failMissingOrder(certProvider.Name(), orderID)
+				return fmt.Errorf("获取证书状态失败: %w", err)
+			}
 			consecutiveErrors++
 			if consecutiveErrors >= 3 {
 				return fmt.Errorf("连续获取证书状态失败: %w", err)
 			}
 			log.Printf("获取状态失败，等待后重试...")
-			time.Sleep(10 * time.Second)
+			sleepContext(ctx, 10*time.Second)
 			continue
 		}
 		consecutiveErrors = 0
 
 		log.Printf("当前状态: %s", status.Status)
+		m.logStateError(m.state.UpdateOrderStatus(certProvider.Name(), orderID, status.Status))
 
 		switch status.Status {
 		case "domain_verify":
 			if status.RecordDomain == "" || status.RecordValue == "" {
 				log.Printf("等待验证信息...")
-				time.Sleep(10 * time.Second)
+				sleepContext(ctx, 10*time.Second)
 				continue
 			}
 
@@ -310,25 +439,27 @@ func (m *Manager) waitForDNSValidation(ctx context.Context, certProvider provide
 			if !dnsRecordAdded || lastRecordDomain != status.RecordDomain {
 				if err := dnsProvider.AddRecord(ctx, domain, status.RecordDomain, status.RecordType, status.RecordValue); err != nil {
 					log.Printf("添加DNS验证记录失败: %v，将重试...", err)
-					time.Sleep(10 * time.Second)
+					sleepContext(ctx, 10*time.Second)
 					continue
 				}
 				dnsRecordAdded = true
 				lastRecordDomain = status.RecordDomain
+				m.logStateError(m.state.AddOrderDNSRecord(certProvider.Name(), orderID, status.RecordDomain, status.RecordType, status.RecordValue))
 			}
 
 			log.Printf("DNS记录已添加，等待验证...")
-			time.Sleep(20 * time.Second)
+			sleepContext(ctx, 20*time.Second)
 
 		case "process":
 			log.Printf("证书正在签发中，请等待...")
-			time.Sleep(20 * time.Second)
+			sleepContext(ctx, 20*time.Second)
 
 		case "certificate":
 			log.Printf("证书已签发成功！")
 			return nil
 
 		case "failed":
+			m.logStateError(m.state.FailOrder(certProvider.Name(), orderID, state.OrderFailed, "证书申请失败，状态为 failed"))
 			// 发送证书申请失败通知
 			if m.notifier != nil {
 				m.notifier.NotifyCertFailed(ctx, domain, "证书申请失败，状态为 failed")
@@ -337,7 +468,7 @@ func (m *Manager) waitForDNSValidation(ctx context.Context, certProvider provide
 
 		default:
 			log.Printf("当前状态: %s，继续等待...", status.Status)
-			time.Sleep(15 * time.Second)
+			sleepContext(ctx, 15*time.Second)
 		}
 	}
 
@@ -349,56 +480,37 @@ func (m *Manager) waitForDNSValidation(ctx context.Context, certProvider provide
 	return fmt.Errorf("等待超时，请检查云平台控制台，订单ID: %s", orderID)
 }
 
-// ContinueOrder 继续处理已存在的订单
-func (m *Manager) ContinueOrder(ctx context.Context, orderID, domain, certProviderName, dnsProviderName string) error {
-	log.Printf("\n========== 继续处理订单: %s (域名: %s) ==========", orderID, domain)
+// sleepContext 等待指定时长，context 取消时立即返回，以便进程能及时退出
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-	// 获取提供商
-	certProvider, err := m.factory.GetCertProvider(certProviderName)
-	if err != nil {
-		return fmt.Errorf("获取证书提供商失败: %w", err)
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
 	}
+}
 
-	dnsProvider, err := m.factory.GetDNSProvider(dnsProviderName)
+// findPendingOrder 查找域名进行中的订单，返回最新的订单ID，没有则返回空字符串
+func (m *Manager) findPendingOrder(ctx context.Context, certProvider provider.CertProvider, domain string) string {
+	orders, err := certProvider.ListPendingOrders(ctx, domain)
 	if err != nil {
-		return fmt.Errorf("获取DNS提供商失败: %w", err)
+		log.Printf("查询进行中的订单失败: %v，将申请新证书", err)
+		return ""
 	}
-
-	// 检查订单状态
-	status, err := certProvider.GetCertificateStatus(ctx, orderID)
-	if err != nil {
-		return fmt.Errorf("获取订单状态失败: %w", err)
+	if len(orders) == 0 {
+		return ""
 	}
 
-	log.Printf("当前订单状态: %s", status.Status)
-
-	if status.Status == "certificate" {
-		// 证书已签发，直接下载
-		log.Printf("证书已签发，开始下载...")
-		cert, err := certProvider.DownloadCertificate(ctx, orderID)
-		if err != nil {
-			// 发送证书申请失败通知
-			if m.notifier != nil {
-				m.notifier.NotifyCertFailed(ctx, domain, fmt.Sprintf("下载证书失败: %v", err))
-			}
-			return fmt.Errorf("下载证书失败: %w", err)
-		}
-		if err := m.storage.SaveCertificate(domain, cert); err != nil {
-			// 发送证书申请失败通知
-			if m.notifier != nil {
-				m.notifier.NotifyCertFailed(ctx, domain, fmt.Sprintf("保存证书失败: %v", err))
-			}
-			return err
-		}
-		// 发送证书申请成功通知
-		if m.notifier != nil {
-			// 使用 orderID 作为 certID（因为 Certificate 结构体没有 CertID 字段）
-			m.notifier.NotifyCertRenewed(ctx, domain, orderID)
-		}
-		return nil
-	}
+	latest := orders[0]
+	log.Printf("发现 %d 个进行中的订单，继续处理最新的订单: %s (状态: %s, 创建时间: %s)",
+		len(orders), latest.OrderID, latest.Status, latest.CreatedAt.Format("2006-01-02 15:04:05"))
+	return latest.OrderID
+}
 
-	// 继续等待验证
+// completeOrder 等待订单验证完成，下载并保存证书
+func (m *Manager) completeOrder(ctx context.Context, certProvider provider.CertProvider, dnsProvider provider.DNSProvider, domain, orderID string) (*storage.SaveResult, error) {
+	// 等待DNS验证（订单已签发时会立即返回）
 	if err := m.waitForDNSValidation(ctx, certProvider, dnsProvider, domain, orderID); err != nil {
 		// 检查是否是超时错误
 		if err.Error() == fmt.Sprintf("等待超时，请检查云平台控制台，订单ID: %s", orderID) {
@@ -406,7 +518,7 @@ func (m *Manager) ContinueOrder(ctx context.Context, orderID, domain, certProvid
 				m.notifier.NotifyDNSValidationTimeout(ctx, domain, orderID)
 			}
 		}
-		return fmt.Errorf("域名验证失败: %w", err)
+		return nil, fmt.Errorf("域名验证失败: %w", err)
 	}
 
 	// 下载证书
@@ -416,27 +528,242 @@ func (m *Manager) ContinueOrder(ctx context.Context, orderID, domain, certProvid
 		if m.notifier != nil {
 			m.notifier.NotifyCertFailed(ctx, domain, fmt.Sprintf("下载证书失败: %v", err))
 		}
-		return fmt.Errorf("下载证书失败: %w", err)
+		return nil, fmt.Errorf("下载证书失败: %w", err)
+	}
+	if cert.OrderID == "" {
+		cert.OrderID = orderID
+	}
+
+	if cert, err = m.prepareCertificate(ctx, domain, cert); err != nil {
+		return nil, err
 	}
 
-	if err := m.storage.SaveCertificate(domain, cert); err != nil {
+	result, err := m.storage.SaveCertificate(domain, cert)
+	if err != nil {
 		// 发送证书申请失败通知
 		if m.notifier != nil {
 			m.notifier.NotifyCertFailed(ctx, domain, fmt.Sprintf("保存证书失败: %v", err))
 		}
-		return fmt.Errorf("保存证书失败: %w", err)
+		return nil, fmt.Errorf("保存证书失败: %w", err)
 	}
 
+	m.recordCertificate(domain, certProvider.Name(), result)
+	m.logStateError(m.state.CompleteOrder(certProvider.Name(), orderID, result.Metadata.CertID, result.Metadata.Fingerprint))
+
 	// 发送证书申请成功通知
 	if m.notifier != nil {
-		// 使用 orderID 作为 certID（因为 Certificate 结构体没有 CertID 字段）
-		m.notifier.NotifyCertRenewed(ctx, domain, orderID)
+		m.notifier.NotifyCertRenewed(ctx, domain, result.Metadata, result.Changed, result.PreviousSerial)
+	}
+
+	return result, nil
+}
+
+// prepareCertificate 保存前整理并校验下载的证书：补全证书链后校验，未通过校验时不保存，并发送 cert_invalid 通知
+func (m *Manager) prepareCertificate(ctx context.Context, domain string, cert *provider.Certificate) (*provider.Certificate, error) {
+	completed, err := m.chain.Complete(ctx, cert)
+	if err == nil {
+		if err = m.validator.CheckIntegrity(completed, domain); err == nil {
+			return completed, nil
+		}
+	}
+
+	log.Printf("证书校验失败，拒绝保存: %v", err)
+	if m.notifier != nil {
+		serial := ""
+		if leaf, parseErr := certutil.ParseCertificatePEM(cert.Certificate); parseErr == nil {
+			serial = certutil.SerialHex(leaf)
+		}
+		m.notifier.NotifyCertInvalid(ctx, domain, serial, err.Error())
+	}
+	return nil, fmt.Errorf("证书校验失败: %w", err)
+}
+
+// recordCertificate 证书发生变化时按保存结果中的元数据记录到状态库
+func (m *Manager) recordCertificate(domain, certProviderName string, result *storage.SaveResult) {
+	if !result.Changed {
+		return
+	}
+
+	meta := result.Metadata
+	m.logStateError(m.state.RecordCertificate(&state.CertificateRecord{
+		Domain:      domain,
+		Provider:    certProviderName,
+		CertID:      meta.CertID,
+		OrderID:     meta.OrderID,
+		Serial:      meta.Serial,
+		Fingerprint: meta.Fingerprint,
+		NotBefore:   meta.NotBefore,
+		NotAfter:    meta.NotAfter,
+	}))
+}
+
+// logStateError 记录状态库写入失败（不影响证书处理流程）
+func (m *Manager) logStateError(err error) {
+	if err != nil {
+		log.Printf("写入状态库失败: %v", err)
+	}
+}
+
+// ContinueOrder 继续处理已存在的订单
+func (m *Manager) ContinueOrder(ctx context.Context, orderID, domain, certProviderName, dnsProviderName string) error {
+	log.Printf("\n========== 继续处理订单: %s (域名: %s) ==========", orderID, domain)
+
+	// 获取提供商
+	certProvider, err := m.factory.GetCertProvider(certProviderName)
+	if err != nil {
+		return fmt.Errorf("获取证书提供商失败: %w", err)
+	}
+
+	dnsProvider, err := m.factory.GetDNSProvider(dnsProviderName)
+	if err != nil {
+		return fmt.Errorf("获取DNS提供商失败: %w", err)
+	}
+
+	// 检查订单状态
+	status, err := certProvider.GetCertificateStatus(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, provider.ErrOrderNotFound) {
+			m.failMissingOrder(certProviderName, orderID)
+		}
+		return fmt.Errorf("获取订单状态失败: %w", err)
+	}
+
+	log.Printf("当前订单状态: %s", status.Status)
+	m.logStateError(m.state.RecordOrder(certProviderName, dnsProviderName, domain, orderID, status.Status))
+
+	result, err := m.completeOrder(ctx, certProvider, dnsProvider, domain, orderID)
+	if err != nil {
+		return err
+	}
+
+	// 与正常签发相同，证书发生变化时部署并执行后置命令，否则同一次运行中 ProcessDomain 会认为证书未变化而跳过
+	if result.Changed {
+		if domainCfg := m.config.FindDomain(domain); domainCfg != nil {
+			if m.ocspEnabled() {
+				if _, _, err := m.updateOCSP(ctx, domain); err != nil {
+					log.Printf("获取 OCSP 响应失败: %v", err)
+				}
+			}
+			m.deployCertificate(ctx, *domainCfg, result)
+		} else {
+			log.Printf("域名 %s 不在配置中，跳过部署和后置命令", domain)
+		}
 	}
 
 	log.Printf("订单 %s 处理完成！", orderID)
 	return nil
 }
 
+// failMissingOrder 云平台上已不存在的订单标记为失败，避免每次启动都尝试恢复
+func (m *Manager) failMissingOrder(certProviderName, orderID string) {
+	log.Printf("订单 %s 在云平台上不存在，标记为失败", orderID)
+	m.logStateError(m.state.FailOrder(certProviderName, orderID, state.OrderFailed, "云平台上订单不存在"))
+}
+
+// ListOrders 列出证书提供商的所有订单
+func (m *Manager) ListOrders(ctx context.Context, certProviderName string) ([]*provider.OrderInfo, error) {
+	certProvider, err := m.factory.GetCertProvider(certProviderName)
+	if err != nil {
+		return nil, fmt.Errorf("获取证书提供商失败: %w", err)
+	}
+
+	return certProvider.ListOrders(ctx)
+}
+
+// GetOrder 获取订单详情
+func (m *Manager) GetOrder(ctx context.Context, certProviderName, orderID string) (*provider.CertificateStatus, error) {
+	certProvider, err := m.factory.GetCertProvider(certProviderName)
+	if err != nil {
+		return nil, fmt.Errorf("获取证书提供商失败: %w", err)
+	}
+
+	status, err := certProvider.GetCertificateStatus(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("获取订单状态失败: %w", err)
+	}
+	return status, nil
+}
+
+// CancelOrder 取消订单
+func (m *Manager) CancelOrder(ctx context.Context, certProviderName, orderID string) error {
+	certProvider, err := m.factory.GetCertProvider(certProviderName)
+	if err != nil {
+		return fmt.Errorf("获取证书提供商失败: %w", err)
+	}
+
+	if err := certProvider.CancelOrder(ctx, orderID); err != nil {
+		return err
+	}
+
+	m.logStateError(m.state.FailOrder(certProviderName, orderID, state.OrderCancelled, "手动取消"))
+	return nil
+}
+
+// State 获取状态库，供状态查询、报表等功能使用
+func (m *Manager) State() *state.Store {
+	return m.state
+}
+
+// ResumeInFlightOrders 恢复状态库中未完成的订单（如守护进程在等待DNS验证时被停止）
+// 处理流程与 ContinueOrder 相同，无需手动指定订单ID和提供商
+func (m *Manager) ResumeInFlightOrders(ctx context.Context) {
+	orders := m.state.InFlightOrders()
+	if len(orders) == 0 {
+		return
+	}
+
+	log.Printf("发现 %d 个未完成的订单，开始恢复处理...", len(orders))
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if m.config.FindDomain(order.Domain) == nil {
+			log.Printf("订单 %s 的域名 %s 已不在配置中，跳过恢复", order.OrderID, order.Domain)
+			continue
+		}
+
+		// DNS 验证超时等原因中断的订单会一直处于未完成状态，超过最长时间后不再恢复
+		if time.Since(order.CreatedAt) > maxInFlightOrderAge {
+			log.Printf("订单 %s (域名: %s) 创建于 %s，超过 %s 仍未完成，标记为失败",
+				order.OrderID, order.Domain, order.CreatedAt.Format("2006-01-02 15:04:05"), maxInFlightOrderAge)
+			m.logStateError(m.state.FailOrder(order.Provider, order.OrderID, state.OrderFailed, "订单超过最长恢复时间仍未完成"))
+			continue
+		}
+
+		if err := m.ContinueOrder(ctx, order.OrderID, order.Domain, order.Provider, order.DNSProvider); err != nil {
+			log.Printf("恢复订单 %s (域名: %s) 失败: %v", order.OrderID, order.Domain, err)
+		}
+	}
+}
+
+// PublishCertificates 按文件布局重新发布所有域名当前版本的证书文件（存储后端支持时）
+func (m *Manager) PublishCertificates() {
+	publisher, ok := m.storage.(storage.Publisher)
+	if !ok {
+		return
+	}
+	for _, domainCfg := range m.config.Domains {
+		if err := publisher.Publish(domainCfg.Domain); err != nil {
+			log.Printf("发布域名 %s 的证书文件失败: %v", domainCfg.Domain, err)
+		}
+	}
+}
+
+// Metadata 返回域名当前保存的证书的元数据
+func (m *Manager) Metadata(domain string) (*provider.Metadata, error) {
+	cert, err := m.storage.LoadCertificate(domain)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Serial == "" {
+		if err := cert.FillMetadata(); err != nil {
+			return nil, fmt.Errorf("解析证书失败: %w", err)
+		}
+	}
+	return &cert.Metadata, nil
+}
+
 // GetConfig 获取配置
 func (m *Manager) GetConfig() *config.Config {
 	return m.config
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/provider"
+	"ssl-manager/internal/state"
+)
+
+func TestContinueOrderDeploysChangedCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	marker := filepath.Join(t.TempDir(), "deployed")
+	domains := []config.DomainConfig{{
+		Domain:    "www.example.com",
+		Provider:  "fake",
+		RenewDays: 7,
+		Deploy:    []config.DeployConfig{execDeploy(marker)},
+	}}
+	m, certProvider := newTestManager(t, ca, domains)
+
+	cert := ca.issue(t, "www.example.com")
+	if err := cert.FillMetadata(); err != nil {
+		t.Fatal(err)
+	}
+	certProvider.certs["order-1"] = cert
+	if err := m.state.RecordOrder("fake", "fake", "www.example.com", "order-1", "domain_verify"); err != nil {
+		t.Fatal(err)
+	}
+
+	m.ResumeInFlightOrders(context.Background())
+
+	if got := strings.TrimSpace(readMarker(t, marker)); got != cert.Serial {
+		t.Fatalf("部署目标记录的序列号 = %q, 期望 %q", got, cert.Serial)
+	}
+	record := m.state.Deployment("www.example.com", "marker")
+	if record == nil || record.Status != state.DeploySucceeded || record.Serial != cert.Serial {
+		t.Fatalf("部署记录 = %+v", record)
+	}
+	if orders := m.state.InFlightOrders(); len(orders) != 0 {
+		t.Fatalf("恢复后仍有未完成的订单: %+v", orders)
+	}
+
+	// 再次处理同一个订单时证书未变化，不重复部署
+	if err := m.ContinueOrder(context.Background(), "order-1", "www.example.com", "fake", "fake"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(readMarker(t, marker), "\n"); got != 1 {
+		t.Fatalf("部署次数 = %d, 期望 1", got)
+	}
+}
+
+func TestContinueOrderFailsMissingOrder(t *testing.T) {
+	ca := newTestCA(t)
+	m, _ := newTestManager(t, ca, []config.DomainConfig{{Domain: "www.example.com", Provider: "fake", RenewDays: 7}})
+	if err := m.state.RecordOrder("fake", "fake", "www.example.com", "gone", "domain_verify"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := m.ContinueOrder(context.Background(), "gone", "www.example.com", "fake", "fake")
+	if !errors.Is(err, provider.ErrOrderNotFound) {
+		t.Fatalf("ContinueOrder 错误 = %v, 期望包含 ErrOrderNotFound", err)
+	}
+	if orders := m.state.InFlightOrders(); len(orders) != 0 {
+		t.Fatalf("云平台上不存在的订单仍未结束: %+v", orders)
+	}
+	assertOrderStatus(t, m, "gone", state.OrderFailed)
+}
+
+func TestResumeExpiresStaleOrder(t *testing.T) {
+	ca := newTestCA(t)
+	m, certProvider := newTestManager(t, ca, []config.DomainConfig{{Domain: "www.example.com", Provider: "fake", RenewDays: 7}})
+	certProvider.certs["stale"] = ca.issue(t, "www.example.com")
+	if err := m.state.RecordOrder("fake", "fake", "www.example.com", "stale", "domain_verify"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 将订单创建时间改为超过最长恢复时间之前
+	path := filepath.Join(m.config.OutputDir, "state.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().Add(-maxInFlightOrderAge - time.Hour).UTC().Format(time.RFC3339)
+	data = createdAtPattern.ReplaceAll(data, []byte(`"created_at": "`+created+`"`))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if m.state, err = state.Open(path); err != nil {
+		t.Fatal(err)
+	}
+
+	m.ResumeInFlightOrders(context.Background())
+
+	if orders := m.state.InFlightOrders(); len(orders) != 0 {
+		t.Fatalf("超过最长恢复时间的订单仍未结束: %+v", orders)
+	}
+	assertOrderStatus(t, m, "stale", state.OrderFailed)
+	if record := m.state.LatestCertificate("www.example.com"); record != nil {
+		t.Fatalf("超过最长恢复时间的订单不应继续签发: %+v", record)
+	}
+}
+
+var createdAtPattern = regexp.MustCompile(`"created_at":\s*"[^"]*"`)
+
+func assertOrderStatus(t *testing.T, m *Manager, orderID, status string) {
+	t.Helper()
+	for _, order := range m.state.Orders() {
+		if order.OrderID == orderID {
+			if order.Status != status {
+				t.Fatalf("订单 %s 状态 = %q, 期望 %q", orderID, order.Status, status)
+			}
+			return
+		}
+	}
+	t.Fatalf("状态库中没有订单 %s", orderID)
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/ocsp"
+	"ssl-manager/internal/provider"
+	"ssl-manager/internal/state"
+	"ssl-manager/internal/storage"
+)
+
+// ocspEnabled 是否开启了 OCSP 检查
+func (m *Manager) ocspEnabled() bool {
+	return m.config.OCSP != nil && m.config.OCSP.Enabled
+}
+
+// RefreshOCSP 查询所有域名当前证书的 OCSP 状态并更新 ocsp.der，证书被吊销时立即重新签发
+func (m *Manager) RefreshOCSP(ctx context.Context) {
+	if !m.ocspEnabled() {
+		return
+	}
+
+	for i := range m.config.Domains {
+		if ctx.Err() != nil {
+			return
+		}
+		domainCfg := &m.config.Domains[i]
+		if err := m.checkOCSP(ctx, domainCfg); err != nil {
+			log.Printf("OCSP 检查失败 [%s]: %v", domainCfg.Domain, err)
+		}
+	}
+}
+
+// checkOCSP 查询域名当前证书的 OCSP 状态，证书被吊销时记录、通知并重新签发
+func (m *Manager) checkOCSP(ctx context.Context, domainCfg *config.DomainConfig) error {
+	domain := domainCfg.Domain
+	resp, serial, err := m.updateOCSP(ctx, domain)
+	if err != nil || resp.Status != ocsp.StatusRevoked {
+		return err
+	}
+
+	reason := ocsp.ReasonString(resp.Reason)
+	log.Printf("OCSP 服务报告证书已被吊销: %s (序列号: %s, 吊销时间: %s, 原因: %s)",
+		domain, serial, resp.RevokedAt.Format("2006-01-02 15:04:05"), reason)
+
+	certProvider, dnsProvider, err := m.factory.GetProvidersForDomain(domainCfg)
+	if err != nil {
+		return fmt.Errorf("获取提供商失败: %w", err)
+	}
+	// 上次重新签发失败时吊销已经记录和通知过
+	if !m.state.IsRevoked(domain, serial) {
+		m.logStateError(m.state.RecordRevocation(&state.RevocationRecord{
+			Domain:    domain,
+			Provider:  certProvider.Name(),
+			Serial:    serial,
+			Reason:    reason,
+			RevokedAt: resp.RevokedAt,
+		}))
+		if m.notifier != nil {
+			m.notifier.NotifyOCSPRevoked(ctx, domain, serial, reason, resp.RevokedAt)
+		}
+	}
+
+	log.Printf("证书已被吊销，开始重新签发...")
+	result, err := m.issueCertificate(ctx, certProvider, dnsProvider, domain)
+	if err != nil {
+		return fmt.Errorf("证书已被吊销，但重新签发失败: %w", err)
+	}
+	if _, _, err := m.updateOCSP(ctx, domain); err != nil {
+		log.Printf("获取新证书的 OCSP 响应失败: %v", err)
+	}
+	m.deployCertificate(ctx, *domainCfg, result)
+	return nil
+}
+
+// updateOCSP 查询域名当前证书的 OCSP 状态，状态正常时保存 OCSP 响应，返回查询结果和证书序列号
+func (m *Manager) updateOCSP(ctx context.Context, domain string) (*ocsp.Response, string, error) {
+	cert, err := m.storage.LoadCertificate(domain)
+	if err != nil {
+		return nil, "", fmt.Errorf("读取证书失败: %w", err)
+	}
+	// 补全证书链以获取签发者证书（通常来自本地缓存）
+	if cert, err = m.chain.Complete(ctx, cert); err != nil {
+		return nil, "", err
+	}
+	certs, err := certutil.ParseCertificatesPEM(cert.Chain)
+	if err != nil {
+		return nil, "", fmt.Errorf("解析证书链失败: %w", err)
+	}
+	if len(certs) < 2 {
+		return nil, "", fmt.Errorf("证书链中没有签发者证书，无法查询 OCSP")
+	}
+	leaf, issuer := certs[0], certs[1]
+	serial := certutil.SerialHex(leaf)
+
+	resp, err := m.ocsp.Query(ctx, leaf, issuer)
+	if errors.Is(err, ocsp.ErrNoResponder) {
+		log.Printf("证书没有 OCSP 服务地址，跳过 OCSP 检查 [%s]", domain)
+		return &ocsp.Response{Status: ocsp.StatusUnknown}, serial, nil
+	}
+	if err != nil {
+		return nil, serial, err
+	}
+
+	switch resp.Status {
+	case ocsp.StatusGood:
+		writer, ok := m.storage.(storage.StapleWriter)
+		if !ok {
+			return resp, serial, nil
+		}
+		if err := writer.SaveOCSP(domain, resp.Raw); err != nil {
+			return nil, serial, err
+		}
+		next := "未提供"
+		if !resp.NextUpdate.IsZero() {
+			next = resp.NextUpdate.Format("2006-01-02 15:04:05")
+		}
+		log.Printf("已更新 OCSP 响应 [%s] (序列号: %s, 下次更新: %s)", domain, serial, next)
+	case ocsp.StatusUnknown:
+		log.Printf("OCSP 服务不认识该证书 [%s] (序列号: %s)，未保存 OCSP 响应", domain, serial)
+	}
+	return resp, serial, nil
+}
+
+// certRevoked 检查证书是否已被 OCSP 检查发现吊销
+func (m *Manager) certRevoked(domain string, cert *provider.Certificate) bool {
+	leaf, err := certutil.ParseCertificatePEM(cert.Certificate)
+	if err != nil {
+		return false
+	}
+	return m.state.IsRevoked(domain, certutil.SerialHex(leaf))
+}
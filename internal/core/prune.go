@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	domainpkg "ssl-manager/internal/domain"
+	"ssl-manager/internal/provider"
+)
+
+// PruneOptions 云端证书清理选项
+type PruneOptions struct {
+	OlderThan time.Duration // 过期或被替代超过该时长的证书才会被清理
+	DryRun    bool          // 只列出待删除的证书，不实际删除
+	All       bool          // 清理账号下所有证书，而不仅是配置中的域名
+}
+
+// PruneResult 单个证书的清理结果
+type PruneResult struct {
+	Provider  string                    // 证书提供商
+	Cert      *provider.CertificateInfo // 证书信息
+	Reason    string                    // 清理原因: expired, superseded
+	Resources []string                  // 仍绑定的云资源（非空时跳过删除）
+	Deleted   bool                      // 是否已删除
+	Err       error                     // 删除失败的错误
+}
+
+// Skipped 是否因仍绑定云资源而跳过
+func (r *PruneResult) Skipped() bool {
+	return len(r.Resources) > 0
+}
+
+// PruneCloud 清理云平台上已过期或已被新证书替代的证书
+func (m *Manager) PruneCloud(ctx context.Context, opts PruneOptions) ([]*PruneResult, error) {
+	var results []*PruneResult
+
+	for _, name := range m.config.CertProviderNames() {
+		certProvider, err := m.factory.GetCertProvider(name)
+		if err != nil {
+			return results, fmt.Errorf("获取证书提供商失败: %w", err)
+		}
+
+		candidates, err := m.findPruneCandidates(ctx, certProvider, opts)
+		if err != nil {
+			log.Printf("查询 %s 待清理证书失败: %v", name, err)
+			continue
+		}
+
+		for _, result := range candidates {
+			resources, err := certProvider.ListBoundResources(ctx, result.Cert.CertID)
+			if err != nil {
+				// 无法确认绑定情况时不删除
+				result.Err = err
+				results = append(results, result)
+				continue
+			}
+			result.Resources = resources
+
+			if !result.Skipped() && !opts.DryRun {
+				if err := certProvider.DeleteCertificate(ctx, result.Cert.CertID); err != nil {
+					result.Err = err
+				} else {
+					result.Deleted = true
+				}
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// findPruneCandidates 查找提供商下待清理的证书
+func (m *Manager) findPruneCandidates(ctx context.Context, certProvider provider.CertProvider, opts PruneOptions) ([]*PruneResult, error) {
+	threshold := time.Now().Add(-opts.OlderThan)
+	var candidates []*PruneResult
+
+	// 1. 过期超过指定时长的证书
+	expired, err := certProvider.ListExpiredCertificates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, cert := range expired {
+		if !opts.All && !m.isManagedCertificate(cert) {
+			continue
+		}
+		if !cert.NotAfter.IsZero() && cert.NotAfter.Before(threshold) {
+			candidates = append(candidates, &PruneResult{
+				Provider: certProvider.Name(),
+				Cert:     cert,
+				Reason:   "expired",
+			})
+		}
+	}
+
+	// 2. 已被新证书替代的证书：同一组域名只保留到期时间最晚的证书，
+	//    且替代证书生效超过指定时长后才清理旧证书，留出部署时间
+	issued, err := certProvider.ListCertificates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]*provider.CertificateInfo)
+	for _, cert := range issued {
+		if !opts.All && !m.isManagedCertificate(cert) {
+			continue
+		}
+		key := certDomainsKey(cert)
+		groups[key] = append(groups[key], cert)
+	}
+	for _, certs := range groups {
+		if len(certs) < 2 {
+			continue
+		}
+		sort.Slice(certs, func(i, j int) bool {
+			return certs[i].NotAfter.After(certs[j].NotAfter)
+		})
+		newest := certs[0]
+		if newest.NotBefore.IsZero() || newest.NotBefore.After(threshold) {
+			continue
+		}
+		for _, cert := range certs[1:] {
+			candidates = append(candidates, &PruneResult{
+				Provider: certProvider.Name(),
+				Cert:     cert,
+				Reason:   "superseded",
+			})
+		}
+	}
+
+	return candidates, nil
+}
+
+// isManagedCertificate 检查证书是否覆盖配置中的某个域名
+func (m *Manager) isManagedCertificate(cert *provider.CertificateInfo) bool {
+	for _, domainCfg := range m.config.Domains {
+		if domainpkg.MatchDomain(cert.Domain, domainCfg.Domain) {
+			return true
+		}
+		for _, san := range cert.Sans {
+			if domainpkg.MatchDomain(san, domainCfg.Domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// certDomainsKey 生成证书覆盖域名的唯一标识，用于识别同一组域名的证书
+func certDomainsKey(cert *provider.CertificateInfo) string {
+	names := map[string]bool{cert.Domain: true}
+	for _, san := range cert.Sans {
+		names[strings.TrimSpace(san)] = true
+	}
+	var keys []string
+	for name := range names {
+		if name != "" {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+	domainpkg "ssl-manager/internal/domain"
+	"ssl-manager/internal/provider"
+	"ssl-manager/internal/state"
+)
+
+// RevokeCertificate 吊销证书，并立即重新签发和部署
+// target 可以是配置中的域名（吊销其当前有效证书），也可以是证书ID
+func (m *Manager) RevokeCertificate(ctx context.Context, target, reason string) error {
+	domainCfg, certProvider, certID, err := m.resolveRevokeTarget(ctx, target)
+	if err != nil {
+		return err
+	}
+	domain := domainCfg.Domain
+
+	dnsProvider, err := m.factory.GetDNSProvider(domainCfg.GetDNSProvider())
+	if err != nil {
+		return fmt.Errorf("获取DNS提供商失败: %w", err)
+	}
+
+	log.Printf("\n========== 吊销证书: %s (域名: %s, 原因: %s) ==========", certID, domain, reason)
+
+	// 吊销后证书详情可能无法再获取，先确定序列号：状态库按序列号阻止被吊销的证书再次部署或回滚上线
+	serial := m.certificateSerial(ctx, domain, certProvider, certID)
+	if serial == "" {
+		log.Printf("警告: 无法确定证书 %s 的序列号，吊销记录将无法阻止该证书再次部署", certID)
+	}
+
+	records, err := certProvider.RevokeCertificate(ctx, certID, reason)
+	if err != nil {
+		return fmt.Errorf("吊销证书失败: %w", err)
+	}
+
+	// 部分提供商要求完成域名验证后才会真正吊销
+	for _, record := range records {
+		log.Printf("添加吊销验证记录: %s (%s) -> %s", record.RR, record.Type, record.Value)
+		if err := dnsProvider.AddRecord(ctx, record.Domain, record.RR, record.Type, record.Value); err != nil {
+			log.Printf("添加吊销验证记录失败: %v，请手动添加", err)
+		}
+	}
+
+	m.logStateError(m.state.RecordRevocation(&state.RevocationRecord{
+		Domain:    domain,
+		Provider:  certProvider.Name(),
+		CertID:    certID,
+		Serial:    serial,
+		Reason:    reason,
+		RevokedAt: time.Now(),
+	}))
+
+	if m.notifier != nil {
+		m.notifier.NotifyCertRevoked(ctx, domain, certID, reason)
+	}
+
+	// 立即重新签发并部署
+	log.Printf("证书已吊销，开始重新签发...")
+	result, err := m.issueCertificate(ctx, certProvider, dnsProvider, domain)
+	if err != nil {
+		return fmt.Errorf("证书已吊销，但重新签发失败: %w", err)
+	}
+	m.deployCertificate(ctx, *domainCfg, result)
+
+	log.Printf("域名 %s 的证书已吊销并重新签发！", domain)
+	return nil
+}
+
+// certificateSerial 返回证书ID对应的证书序列号：优先从证书详情解析，获取失败时查找状态库和当前保存的证书
+func (m *Manager) certificateSerial(ctx context.Context, domain string, certProvider provider.CertProvider, certID string) string {
+	if cert, err := certProvider.GetCertificateDetail(ctx, certID); err == nil {
+		if leaf, err := certutil.ParseCertificatePEM(cert.Certificate); err == nil {
+			return certutil.SerialHex(leaf)
+		}
+	} else {
+		log.Printf("获取证书详情失败: %v", err)
+	}
+
+	records := m.state.Certificates(domain)
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].CertID == certID && records[i].Serial != "" {
+			return records[i].Serial
+		}
+	}
+	if meta, err := m.Metadata(domain); err == nil && meta.CertID == certID {
+		return meta.Serial
+	}
+	return ""
+}
+
+// resolveRevokeTarget 解析吊销目标，返回域名配置、证书提供商和证书ID
+func (m *Manager) resolveRevokeTarget(ctx context.Context, target string) (*config.DomainConfig, provider.CertProvider, string, error) {
+	// 目标为配置中的域名：吊销其当前有效证书
+	if domainCfg := m.config.FindDomain(target); domainCfg != nil {
+		certProvider, err := m.factory.GetCertProvider(domainCfg.GetCertProvider())
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("获取证书提供商失败: %w", err)
+		}
+
+		cert, err := certProvider.FindValidCertificate(ctx, target, 0)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("查询证书失败: %w", err)
+		}
+		if cert == nil {
+			return nil, nil, "", fmt.Errorf("未找到域名 %s 的有效证书", target)
+		}
+		return domainCfg, certProvider, cert.CertID, nil
+	}
+
+	// 目标为证书ID：在已配置的证书提供商中查找
+	for _, name := range m.config.CertProviderNames() {
+		certProvider, err := m.factory.GetCertProvider(name)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("获取证书提供商失败: %w", err)
+		}
+
+		certs, err := certProvider.ListCertificates(ctx)
+		if err != nil {
+			log.Printf("查询 %s 证书列表失败: %v", name, err)
+			continue
+		}
+
+		for _, cert := range certs {
+			if cert.CertID != target {
+				continue
+			}
+			domainCfg := m.findDomainForCertificate(cert, name)
+			if domainCfg == nil {
+				return nil, nil, "", fmt.Errorf("证书 %s (%s) 不属于配置中的任何域名", target, cert.Domain)
+			}
+			return domainCfg, certProvider, cert.CertID, nil
+		}
+	}
+
+	return nil, nil, "", fmt.Errorf("%s 既不是已配置的域名，也不是已签发证书的ID", target)
+}
+
+// findDomainForCertificate 查找使用该证书提供商且被证书覆盖的域名配置
+func (m *Manager) findDomainForCertificate(cert *provider.CertificateInfo, certProviderName string) *config.DomainConfig {
+	for i := range m.config.Domains {
+		domainCfg := &m.config.Domains[i]
+		if domainCfg.GetCertProvider() != certProviderName {
+			continue
+		}
+		if domainpkg.MatchDomain(cert.Domain, domainCfg.Domain) {
+			return domainCfg
+		}
+		for _, san := range cert.Sans {
+			if domainpkg.MatchDomain(san, domainCfg.Domain) {
+				return domainCfg
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/provider"
+)
+
+func TestRevokedCertificateIsNotDeployedOrRolledBack(t *testing.T) {
+	ca := newTestCA(t)
+	marker := filepath.Join(t.TempDir(), "deployed")
+	domains := []config.DomainConfig{{
+		Domain:    "www.example.com",
+		Provider:  "fake",
+		RenewDays: 7,
+		Deploy:    []config.DeployConfig{execDeploy(marker)},
+	}}
+	m, certProvider := newTestManager(t, ca, domains)
+	ctx := context.Background()
+
+	older := ca.issue(t, "www.example.com")
+	current := ca.issue(t, "www.example.com")
+	certProvider.certs["cert-1"] = older
+	certProvider.certs["cert-2"] = current
+	for _, id := range []string{"cert-1", "cert-2"} {
+		if err := m.ContinueOrder(ctx, id, "www.example.com", "fake", "fake"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta, err := m.Metadata("www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentSerial := meta.Serial
+
+	// 吊销当前证书，fake 提供商不支持申请证书，重新签发失败
+	certProvider.valid = &provider.CertificateInfo{CertID: "cert-2"}
+	if err := m.RevokeCertificate(ctx, "www.example.com", "unspecified"); err == nil {
+		t.Fatal("重新签发应该失败")
+	}
+	revocations := m.state.Revocations()
+	if len(revocations) != 1 || revocations[0].Serial != currentSerial {
+		t.Fatalf("吊销记录 = %+v, 期望序列号 %s", revocations, currentSerial)
+	}
+
+	deployed := readMarker(t, marker)
+	if _, err := m.Deploy(ctx, "www.example.com", ""); !errors.Is(err, errCertRevoked) {
+		t.Fatalf("部署被吊销的证书: err = %v", err)
+	}
+	m.retryDeployments(ctx, "www.example.com")
+	if got := readMarker(t, marker); got != deployed {
+		t.Fatalf("被吊销的证书被部署: %q", got)
+	}
+
+	// 可以回滚到未吊销的旧证书，但不能再回滚到被吊销的证书
+	if _, err := m.Rollback(ctx, "www.example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Rollback(ctx, "www.example.com", currentSerial)
+	if err == nil || !strings.Contains(err.Error(), "已被吊销") {
+		t.Fatalf("回滚到被吊销的证书: err = %v", err)
+	}
+	if meta, err := m.Metadata("www.example.com"); err != nil || meta.Serial == currentSerial {
+		t.Fatalf("当前证书 = %+v, %v", meta, err)
+	}
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"ssl-manager/internal/storage"
+)
+
+// History 返回域名保存过的证书版本（按保存时间从新到旧）
+func (m *Manager) History(domain string) ([]*storage.Version, error) {
+	return m.storage.History(domain)
+}
+
+// Rollback 将域名的证书回滚到历史版本并重新部署，version 为空时回滚到上一个版本
+func (m *Manager) Rollback(ctx context.Context, domain, version string) (*storage.SaveResult, error) {
+	domainCfg := m.config.FindDomain(domain)
+	if domainCfg == nil {
+		return nil, fmt.Errorf("域名 %s 不在配置中", domain)
+	}
+
+	rollbacker, ok := m.storage.(storage.Rollbacker)
+	if !ok {
+		return nil, fmt.Errorf("存储后端 %s 不支持回滚", m.storage.Name())
+	}
+
+	// 被吊销的证书不能重新上线
+	if target := m.rollbackTarget(domain, version); target != nil && m.state.IsRevoked(domain, target.Serial) {
+		return nil, fmt.Errorf("版本 %s 的证书 (序列号: %s) 已被吊销，不能回滚到该版本", target.Name, target.Serial)
+	}
+
+	result, err := rollbacker.Rollback(domain, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// 记录到状态库，部署一致性检查以回滚时间计算宽限期
+	if result.Metadata.Serial != "" {
+		m.recordCertificate(domain, domainCfg.GetCertProvider(), result)
+	}
+
+	m.deployCertificate(ctx, *domainCfg, result)
+	return result, nil
+}
+
+// rollbackTarget 返回回滚的目标版本（与存储后端的选择规则相同），找不到时返回 nil，由存储后端报告错误
+func (m *Manager) rollbackTarget(domain, version string) *storage.Version {
+	versions, err := m.storage.History(domain)
+	if err != nil {
+		return nil
+	}
+	for i, v := range versions {
+		if version == "" && v.Current && i+1 < len(versions) {
+			return versions[i+1]
+		}
+		if version != "" && (v.Name == version || v.Serial == version) {
+			return v
+		}
+	}
+	return nil
+}
@@ -1,67 +1,164 @@
 package core
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"time"
 
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
 	domainpkg "ssl-manager/internal/domain"
+	"ssl-manager/internal/probe"
+	"ssl-manager/internal/provider"
 )
 
 // Validator 证书验证器
-type Validator struct{}
+type Validator struct {
+	roots *x509.CertPool // 校验证书链使用的根证书，nil 表示系统根证书
+}
+
+// NewValidator 创建验证器，roots 为 nil 时使用系统根证书校验证书链
+func NewValidator(roots *x509.CertPool) *Validator {
+	return &Validator{roots: roots}
+}
+
+// CheckEndpoints 检查域名的所有线上端点，每个端点单独返回结果
+// probes 为空时检查 域名:443
+func (v *Validator) CheckEndpoints(ctx context.Context, domain string, probes []config.ProbeConfig) []*probe.Result {
+	if len(probes) == 0 {
+		probes = []config.ProbeConfig{{}}
+	}
 
-// NewValidator 创建验证器
-func NewValidator() *Validator {
-	return &Validator{}
+	var results []*probe.Result
+	for i := range probes {
+		results = append(results, probe.Probe(ctx, domain, &probes[i])...)
+	}
+	return results
 }
 
-// CheckCertExpiry 检查证书有效期，返回过期时间和证书覆盖的域名列表
-func (v *Validator) CheckCertExpiry(domain string) (time.Time, []string, error) {
-	conn, err := tls.Dial("tcp", domain+":443", &tls.Config{
-		InsecureSkipVerify: true,
-	})
+// NeedRenew 判断是否需要续期（检查所有端点的过期时间和域名匹配，任一端点需要续期即续期）
+// 返回的过期时间为所有端点中最早的过期时间
+func (v *Validator) NeedRenew(ctx context.Context, domain string, renewDays int, probes []config.ProbeConfig) (bool, time.Time, error) {
+	var needRenew bool
+	var earliest time.Time
+
+	for _, result := range v.CheckEndpoints(ctx, domain, probes) {
+		if result.Err != nil {
+			log.Printf("无法获取 %s 的证书信息 [%s]: %v，将尝试申请新证书", domain, result.Endpoint, result.Err)
+			needRenew = true
+			continue
+		}
+
+		leaf := result.Leaf()
+		if earliest.IsZero() || leaf.NotAfter.Before(earliest) {
+			earliest = leaf.NotAfter
+		}
+
+		// 检查域名是否匹配
+		certDomains := result.Domains()
+		if !v.matchDomain(certDomains, domain) {
+			log.Printf("线上证书域名不匹配 [%s] (证书域名: %v, 目标域名: %s)，需要重新申请", result.Endpoint, certDomains, domain)
+			needRenew = true
+			continue
+		}
+
+		daysUntilExpiry := int(time.Until(leaf.NotAfter).Hours() / 24)
+		log.Printf("域名 %s 的证书将在 %d 天后过期 (%s) [%s]", domain, daysUntilExpiry, leaf.NotAfter.Format("2006-01-02"), result.Endpoint)
+		if daysUntilExpiry <= renewDays {
+			needRenew = true
+		}
+	}
+
+	return needRenew, earliest, nil
+}
+
+// NeedRenewLocal 根据本地保存的证书判断是否需要续期（检查过期时间、域名覆盖和私钥匹配）
+func (v *Validator) NeedRenewLocal(cert *provider.Certificate, domain string, renewDays int) (bool, time.Time, error) {
+	leaf, err := certutil.ParseCertificatePEM(cert.Certificate)
 	if err != nil {
-		return time.Time{}, nil, fmt.Errorf("连接失败: %w", err)
+		return true, time.Time{}, fmt.Errorf("解析本地证书失败: %w", err)
 	}
-	defer conn.Close()
 
-	certs := conn.ConnectionState().PeerCertificates
-	if len(certs) == 0 {
-		return time.Time{}, nil, fmt.Errorf("未找到证书")
+	var certDomains []string
+	if leaf.Subject.CommonName != "" {
+		certDomains = append(certDomains, leaf.Subject.CommonName)
+	}
+	certDomains = append(certDomains, leaf.DNSNames...)
+	if !v.matchDomain(certDomains, domain) {
+		log.Printf("本地证书域名不匹配 (证书域名: %v, 目标域名: %s)，需要重新申请", certDomains, domain)
+		return true, leaf.NotAfter, nil
 	}
 
-	cert := certs[0]
-	// 收集证书覆盖的所有域名（CN + SANs）
-	var domains []string
-	if cert.Subject.CommonName != "" {
-		domains = append(domains, cert.Subject.CommonName)
+	if _, err := tls.X509KeyPair([]byte(cert.Certificate), []byte(cert.PrivateKey)); err != nil {
+		log.Printf("本地私钥与证书不匹配: %v，需要重新申请", err)
+		return true, leaf.NotAfter, nil
 	}
-	domains = append(domains, cert.DNSNames...)
 
-	return cert.NotAfter, domains, nil
+	daysUntilExpiry := int(time.Until(leaf.NotAfter).Hours() / 24)
+	log.Printf("域名 %s 的本地证书将在 %d 天后过期 (%s)", domain, daysUntilExpiry, leaf.NotAfter.Format("2006-01-02"))
+
+	return daysUntilExpiry <= renewDays, leaf.NotAfter, nil
 }
 
-// NeedRenew 判断是否需要续期（检查过期时间和域名匹配）
-func (v *Validator) NeedRenew(domain string, renewDays int) (bool, time.Time, error) {
-	expiry, certDomains, err := v.CheckCertExpiry(domain)
+// CheckIntegrity 保存前校验下载的证书：未过期、覆盖目标域名、包含与证书匹配的私钥、证书链可以验证到受信任的根证书
+func (v *Validator) CheckIntegrity(cert *provider.Certificate, domain string) error {
+	leaf, err := certutil.ParseCertificatePEM(cert.Certificate)
 	if err != nil {
-		log.Printf("无法获取 %s 的证书信息: %v，将尝试申请新证书", domain, err)
-		return true, time.Time{}, nil
+		return fmt.Errorf("解析证书失败: %w", err)
 	}
 
-	// 检查域名是否匹配
-	matched := v.matchDomain(certDomains, domain)
-	if !matched {
-		log.Printf("线上证书域名不匹配 (证书域名: %v, 目标域名: %s)，需要重新申请", certDomains, domain)
-		return true, expiry, nil
+	now := time.Now()
+	if !now.Before(leaf.NotAfter) {
+		return fmt.Errorf("证书已于 %s 过期", leaf.NotAfter.Format("2006-01-02 15:04:05"))
 	}
 
-	daysUntilExpiry := int(time.Until(expiry).Hours() / 24)
-	log.Printf("域名 %s 的证书将在 %d 天后过期 (%s)", domain, daysUntilExpiry, expiry.Format("2006-01-02"))
+	var certDomains []string
+	if leaf.Subject.CommonName != "" {
+		certDomains = append(certDomains, leaf.Subject.CommonName)
+	}
+	certDomains = append(certDomains, leaf.DNSNames...)
+	if !v.matchDomain(certDomains, domain) {
+		return fmt.Errorf("证书域名不匹配 (证书域名: %v, 目标域名: %s)", certDomains, domain)
+	}
 
-	return daysUntilExpiry <= renewDays, expiry, nil
+	if cert.PrivateKey == "" {
+		return fmt.Errorf("下载的证书缺少私钥")
+	}
+	if _, err := tls.X509KeyPair([]byte(cert.Certificate), []byte(cert.PrivateKey)); err != nil {
+		return fmt.Errorf("私钥与证书不匹配: %w", err)
+	}
+
+	// 证书链中除叶子证书以外的证书作为中间证书
+	intermediates := x509.NewCertPool()
+	count := 0
+	if cert.Chain != "" {
+		chain, err := certutil.ParseCertificatesPEM(cert.Chain)
+		if err != nil {
+			return fmt.Errorf("解析证书链失败: %w", err)
+		}
+		for _, c := range chain {
+			if !c.Equal(leaf) {
+				intermediates.AddCert(c)
+				count++
+			}
+		}
+	}
+	opts := x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		if count == 0 {
+			return fmt.Errorf("证书链不完整（只包含叶子证书，缺少中间证书）: %w", err)
+		}
+		return fmt.Errorf("证书链验证失败: %w", err)
+	}
+	return nil
 }
 
 // matchDomain 检查目标域名是否在证书域名列表中匹配
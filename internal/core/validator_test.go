@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+)
+
+func TestCheckIntegrityRejectsMissingPrivateKey(t *testing.T) {
+	ca := newTestCA(t)
+	roots, err := certutil.LoadCertPool(ca.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewValidator(roots)
+
+	cert := ca.issue(t, "www.example.com")
+	if err := v.CheckIntegrity(cert, "www.example.com"); err != nil {
+		t.Fatalf("完整的证书未通过校验: %v", err)
+	}
+
+	cert.PrivateKey = ""
+	if err := v.CheckIntegrity(cert, "www.example.com"); err == nil || !strings.Contains(err.Error(), "缺少私钥") {
+		t.Fatalf("缺少私钥的证书校验结果 = %v", err)
+	}
+}
+
+func TestContinueOrderRejectsDownloadWithoutPrivateKey(t *testing.T) {
+	ca := newTestCA(t)
+	domains := []config.DomainConfig{{Domain: "www.example.com", Provider: "fake", RenewDays: 7}}
+	m, certProvider := newTestManager(t, ca, domains)
+
+	complete := ca.issue(t, "www.example.com")
+	certProvider.certs["order-1"] = complete
+	if err := m.ContinueOrder(context.Background(), "order-1", "www.example.com", "fake", "fake"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一张证书重新下载时缺少私钥，不能被当作未变化的证书接受
+	withoutKey := *complete
+	withoutKey.PrivateKey = ""
+	certProvider.certs["order-2"] = &withoutKey
+	if err := m.ContinueOrder(context.Background(), "order-2", "www.example.com", "fake", "fake"); err == nil {
+		t.Fatal("缺少私钥的证书被保存")
+	}
+
+	saved, err := m.storage.LoadCertificate("www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.PrivateKey != complete.PrivateKey {
+		t.Fatal("已保存的私钥被修改")
+	}
+}
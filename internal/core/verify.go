@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/state"
+)
+
+// VerifyResult 单个线上端点的部署校验结果
+type VerifyResult struct {
+	Domain         string    // 域名
+	Endpoint       string    // 线上端点
+	ExpectedSerial string    // 本地证书序列号
+	ServedSerial   string    // 线上证书序列号
+	ServedNotAfter time.Time // 线上证书过期时间
+	Drift          bool      // 线上证书与本地证书不一致
+	Err            error     // 校验失败的错误
+}
+
+// Verify 对比本地保存的证书与各线上端点实际使用的证书，domain 为空时校验所有域名
+func (m *Manager) Verify(ctx context.Context, domain string) ([]*VerifyResult, error) {
+	var domains []*config.DomainConfig
+	if domain != "" {
+		domainCfg := m.config.FindDomain(domain)
+		if domainCfg == nil {
+			return nil, fmt.Errorf("域名 %s 不在配置中", domain)
+		}
+		domains = append(domains, domainCfg)
+	} else {
+		for i := range m.config.Domains {
+			domains = append(domains, &m.config.Domains[i])
+		}
+	}
+
+	var results []*VerifyResult
+	for _, domainCfg := range domains {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		results = append(results, m.verifyDomain(ctx, domainCfg)...)
+	}
+	return results, nil
+}
+
+// verifyDomain 校验单个域名的所有线上端点
+func (m *Manager) verifyDomain(ctx context.Context, domainCfg *config.DomainConfig) []*VerifyResult {
+	domain := domainCfg.Domain
+
+	cert, err := m.storage.LoadCertificate(domain)
+	if err != nil {
+		return []*VerifyResult{{Domain: domain, Err: fmt.Errorf("读取本地证书失败: %w", err)}}
+	}
+	local, err := certutil.ParseCertificatePEM(cert.Certificate)
+	if err != nil {
+		return []*VerifyResult{{Domain: domain, Err: fmt.Errorf("解析本地证书失败: %w", err)}}
+	}
+	expected := certutil.Fingerprint(local)
+
+	var results []*VerifyResult
+	for _, probeResult := range m.validator.CheckEndpoints(ctx, domain, domainCfg.Probes) {
+		result := &VerifyResult{
+			Domain:         domain,
+			Endpoint:       probeResult.Endpoint,
+			ExpectedSerial: certutil.SerialHex(local),
+			Err:            probeResult.Err,
+		}
+		if served := probeResult.Leaf(); served != nil {
+			result.ServedSerial = certutil.SerialHex(served)
+			result.ServedNotAfter = served.NotAfter
+			result.Drift = certutil.Fingerprint(served) != expected
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// CheckDrift 检查所有域名的部署一致性，证书更新超过宽限期后线上仍为旧证书时发送 deploy_drift 通知
+func (m *Manager) CheckDrift(ctx context.Context) {
+	results, err := m.Verify(ctx, "")
+	if err != nil {
+		log.Printf("部署一致性检查中断: %v", err)
+	}
+
+	for _, result := range results {
+		m.reportDrift(ctx, result)
+	}
+}
+
+// reportDrift 处理单个端点的校验结果：不一致时发送 deploy_drift 通知，恢复一致时清除记录
+// 同一端点只在首次发现不一致或本地、线上证书序列号变化时通知，避免每次检查重复通知
+func (m *Manager) reportDrift(ctx context.Context, result *VerifyResult) {
+	if result.Err != nil {
+		log.Printf("部署一致性检查失败 [%s %s]: %v", result.Domain, result.Endpoint, result.Err)
+		return
+	}
+	if !result.Drift {
+		m.logStateError(m.state.ClearDrift(result.Domain, result.Endpoint))
+		return
+	}
+
+	// 刚更新的证书可能尚未部署完成，宽限期内只记录日志
+	grace := time.Duration(m.config.DriftGrace) * time.Hour
+	if record := m.state.LatestCertificate(result.Domain); record != nil && time.Since(record.SavedAt) < grace {
+		log.Printf("端点 %s 仍在使用旧证书 (序列号: %s)，证书更新于 %s，仍在宽限期内",
+			result.Endpoint, result.ServedSerial, record.SavedAt.Format("2006-01-02 15:04:05"))
+		return
+	}
+
+	log.Printf("证书部署不一致: %s 的端点 %s 仍在使用旧证书 (线上序列号: %s, 本地序列号: %s)",
+		result.Domain, result.Endpoint, result.ServedSerial, result.ExpectedSerial)
+	if m.state.DriftNotified(result.Domain, result.Endpoint, result.ExpectedSerial, result.ServedSerial) {
+		log.Printf("该不一致已通知过，跳过 deploy_drift 通知")
+		return
+	}
+	if m.notifier != nil {
+		if err := m.notifier.NotifyDeployDrift(ctx, result.Domain, result.Endpoint, result.ExpectedSerial, result.ServedSerial, result.ServedNotAfter); err != nil {
+			log.Printf("发送 deploy_drift 通知失败: %v", err)
+			return
+		}
+	}
+	m.logStateError(m.state.RecordDrift(&state.DriftRecord{
+		Domain:         result.Domain,
+		Endpoint:       result.Endpoint,
+		ExpectedSerial: result.ExpectedSerial,
+		ServedSerial:   result.ServedSerial,
+		NotifiedAt:     time.Now(),
+	}))
+}
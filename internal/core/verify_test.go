@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/notification"
+)
+
+// serveCertificate 启动使用 cert 的 TLS 服务，返回探测配置
+func serveCertificate(t *testing.T, cert *tls.Certificate) config.ProbeConfig {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{*cert}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.ProbeConfig{Host: host, Port: portNum}
+}
+
+func TestDriftNotifiedOncePerServedSerial(t *testing.T) {
+	ca := newTestCA(t)
+	served := ca.issue(t, "old.example.com")
+	servedPair, err := tls.X509KeyPair([]byte(served.Certificate), []byte(served.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe := serveCertificate(t, &servedPair)
+
+	domains := []config.DomainConfig{{
+		Domain:      "www.example.com",
+		Provider:    "fake",
+		RenewSource: config.RenewSourceLive,
+		Probes:      []config.ProbeConfig{probe},
+	}}
+	m, certProvider := newTestManager(t, ca, domains)
+	m.config.DriftGrace = 0
+	webhook := &eventWebhook{event: notification.EventDeployDrift}
+	hook := httptest.NewServer(webhook)
+	defer hook.Close()
+	m.notifier = notification.NewWebhookNotifier(&config.WebhookConfig{Enabled: true, URL: hook.URL})
+	ctx := context.Background()
+
+	certProvider.certs["cert-1"] = ca.issueFor(t, "www.example.com", 10*24*time.Hour)
+	if err := m.ContinueOrder(ctx, "cert-1", "www.example.com", "fake", "fake"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重复检查同一不一致只通知一次，续期检查走同一去重逻辑
+	m.CheckDrift(ctx)
+	m.CheckDrift(ctx)
+	renew, _, err := m.needRenew(ctx, &m.config.Domains[0])
+	if err != nil || renew {
+		t.Fatalf("needRenew = %v, %v, 期望部署不一致时跳过续期", renew, err)
+	}
+	if got := webhook.count(); got != 1 {
+		t.Fatalf("deploy_drift 通知 %d 次, 期望 1 次", got)
+	}
+
+	// 本地证书更新后序列号变化，重新通知
+	certProvider.certs["cert-2"] = ca.issueFor(t, "www.example.com", 10*24*time.Hour)
+	if err := m.ContinueOrder(ctx, "cert-2", "www.example.com", "fake", "fake"); err != nil {
+		t.Fatal(err)
+	}
+	if renew, _, err := m.needRenew(ctx, &m.config.Domains[0]); err != nil || renew {
+		t.Fatalf("needRenew = %v, %v", renew, err)
+	}
+	m.CheckDrift(ctx)
+	if got := webhook.count(); got != 2 {
+		t.Fatalf("deploy_drift 通知 %d 次, 期望 2 次", got)
+	}
+	if event := webhook.events[1]; event.Domain != "www.example.com" || event.Data["served_serial"] == "" {
+		t.Fatalf("通知内容 = %+v", event)
+	}
+}
@@ -0,0 +1,381 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	openapiutil "github.com/alibabacloud-go/darabonba-openapi/v2/utils"
+	"github.com/alibabacloud-go/tea/dara"
+	"github.com/alibabacloud-go/tea/tea"
+
+	"ssl-manager/internal/config"
+)
+
+// 阿里云部署目标的默认值
+const (
+	defaultAliyunRegion    = "cn-hangzhou"
+	defaultCASEndpoint     = "cas.aliyuncs.com"
+	defaultCASRegion       = "cn-hangzhou"
+	defaultSLBListenerPort = 443
+)
+
+// aliyunAPI 阿里云 RPC 风格 API 的客户端，接入地址带 http:// 前缀时使用 HTTP（用于本地模拟服务）
+type aliyunAPI struct {
+	client  *openapi.Client
+	version string
+}
+
+// newAliyunAPI 创建阿里云 API 客户端，endpoint 为空时使用 defaultEndpoint
+func newAliyunAPI(cfg *config.AliyunConfig, endpoint, defaultEndpoint, version string) (*aliyunAPI, error) {
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	protocol := "HTTPS"
+	if strings.HasPrefix(endpoint, "http://") {
+		protocol = "HTTP"
+	}
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "http://"), "https://")
+
+	client, err := openapi.NewClient(&openapi.Config{
+		AccessKeyId:     tea.String(cfg.AccessKeyID),
+		AccessKeySecret: tea.String(cfg.AccessKeySecret),
+		Endpoint:        tea.String(strings.TrimSuffix(endpoint, "/")),
+		Protocol:        tea.String(protocol),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建阿里云客户端失败 (%s): %w", endpoint, err)
+	}
+	return &aliyunAPI{client: client, version: version}, nil
+}
+
+// call 调用 API，query 中的列表按 Name.N.Field 展开，响应体解析到 out（为 nil 时忽略）
+// SDK 不支持 context，按 ctx 的剩余时间设置请求超时
+func (a *aliyunAPI) call(ctx context.Context, action string, query map[string]interface{}, out interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	params := &openapi.Params{
+		Action:      tea.String(action),
+		Version:     tea.String(a.version),
+		Protocol:    tea.String("HTTPS"),
+		Pathname:    tea.String("/"),
+		Method:      tea.String("POST"),
+		AuthType:    tea.String("AK"),
+		Style:       tea.String("RPC"),
+		ReqBodyType: tea.String("formData"),
+		BodyType:    tea.String("json"),
+	}
+	request := &openapi.OpenApiRequest{Query: openapiutil.Query(query)}
+
+	runtime := &dara.RuntimeOptions{}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := int(time.Until(deadline).Milliseconds())
+		if timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+		runtime.ReadTimeout = tea.Int(timeout)
+		runtime.ConnectTimeout = tea.Int(timeout)
+	}
+
+	resp, err := a.client.CallApi(params, request, runtime)
+	if err != nil {
+		return fmt.Errorf("调用 %s 失败: %w", action, err)
+	}
+	if out == nil {
+		return nil
+	}
+	data, err := json.Marshal(resp["body"])
+	if err != nil {
+		return fmt.Errorf("解析 %s 响应失败: %w", action, err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("解析 %s 响应失败: %w", action, err)
+	}
+	return nil
+}
+
+// casUploader 将证书上传到阿里云数字证书管理服务 (CAS)
+type casUploader struct {
+	api    *aliyunAPI
+	region string
+}
+
+// newCASUploader 创建证书上传客户端
+func newCASUploader(cfg *config.AliyunConfig, deployCfg *config.AliyunDeployConfig) (*casUploader, error) {
+	api, err := newAliyunAPI(cfg, deployCfg.CASEndpoint, defaultCASEndpoint, "2020-04-07")
+	if err != nil {
+		return nil, err
+	}
+	region := deployCfg.CASRegion
+	if region == "" {
+		region = defaultCASRegion
+	}
+	return &casUploader{api: api, region: region}, nil
+}
+
+// casCertificate CAS 中的证书
+type casCertificate struct {
+	ID   int64
+	Name string
+}
+
+// regionID 返回 ALB、OSS 等产品引用 CAS 证书时使用的 <证书ID>-<地域> 格式
+func (c *casCertificate) regionID(region string) string {
+	return fmt.Sprintf("%d-%s", c.ID, region)
+}
+
+// casCertificateName 返回证书在 CAS 中的名称：域名加序列号前缀
+// 同一张证书的名称不变，多个部署目标、重试和重启后都复用同一次上传
+func casCertificateName(domain, serial string) string {
+	name := strings.NewReplacer("*", "wildcard", ".", "_").Replace(domain)
+	if len(serial) > 16 {
+		serial = serial[:16]
+	}
+	return name + "-" + serial
+}
+
+// Upload 上传证书，同名证书已存在时直接返回
+func (c *casUploader) Upload(ctx context.Context, req *Request) (*casCertificate, error) {
+	cert := req.Certificate
+	name := casCertificateName(req.Domain, cert.Serial)
+
+	existing, err := c.find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	certPEM := cert.Chain
+	if certPEM == "" {
+		certPEM = cert.Certificate
+	}
+	var resp struct {
+		CertId int64
+	}
+	err = c.api.call(ctx, "UploadUserCertificate", map[string]interface{}{
+		"Name": name,
+		"Cert": certPEM,
+		"Key":  cert.PrivateKey,
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("上传证书到阿里云CAS失败: %w", err)
+	}
+	if resp.CertId == 0 {
+		return nil, fmt.Errorf("上传证书到阿里云CAS失败: 响应中没有证书ID")
+	}
+	log.Printf("[阿里云] 证书已上传到CAS: %s (证书ID: %d)", name, resp.CertId)
+	return &casCertificate{ID: resp.CertId, Name: name}, nil
+}
+
+// find 按名称查找已上传的证书
+func (c *casUploader) find(ctx context.Context, name string) (*casCertificate, error) {
+	var resp struct {
+		CertificateOrderList []struct {
+			CertificateId int64
+			Name          string
+		}
+	}
+	err := c.api.call(ctx, "ListUserCertificateOrder", map[string]interface{}{
+		"OrderType": "UPLOAD",
+		"Keyword":   name,
+		"ShowSize":  "50",
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("查询阿里云CAS证书失败: %w", err)
+	}
+	// Keyword 为模糊匹配，需要精确过滤
+	for _, item := range resp.CertificateOrderList {
+		if item.Name == name && item.CertificateId != 0 {
+			return &casCertificate{ID: item.CertificateId, Name: item.Name}, nil
+		}
+	}
+	return nil, nil
+}
+
+// aliyunTarget 阿里云部署目标：上传证书到 CAS 后绑定到 CDN/DCDN 域名、SLB/ALB 监听或 OSS 自定义域名
+type aliyunTarget struct {
+	typ    string
+	cfg    config.AliyunDeployConfig // 已填充默认值
+	cas    *casUploader
+	api    *aliyunAPI // CDN、DCDN、SLB、ALB
+	oss    *ossClient // OSS
+	region string
+}
+
+// newAliyunTarget 创建阿里云部署目标，domain 为证书域名
+func newAliyunTarget(domain, typ string, cfg *config.AliyunDeployConfig, providers *config.ProvidersConfig) (*aliyunTarget, error) {
+	if providers == nil || providers.Aliyun == nil {
+		return nil, fmt.Errorf("未配置 providers.aliyun 凭证")
+	}
+	credentials := providers.Aliyun
+
+	t := &aliyunTarget{typ: typ}
+	if cfg != nil {
+		t.cfg = *cfg
+	}
+	if t.cfg.Domain == "" {
+		t.cfg.Domain = domain
+	}
+	if t.cfg.ListenerPort == 0 {
+		t.cfg.ListenerPort = defaultSLBListenerPort
+	}
+	t.region = t.cfg.Region
+	if t.region == "" {
+		t.region = credentials.Region
+	}
+	if t.region == "" {
+		t.region = defaultAliyunRegion
+	}
+
+	var err error
+	if t.cas, err = newCASUploader(credentials, &t.cfg); err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case config.DeployAliyunCDN:
+		t.api, err = newAliyunAPI(credentials, t.cfg.Endpoint, "cdn.aliyuncs.com", "2018-05-10")
+	case config.DeployAliyunDCDN:
+		t.api, err = newAliyunAPI(credentials, t.cfg.Endpoint, "dcdn.aliyuncs.com", "2018-01-15")
+	case config.DeployAliyunSLB:
+		t.api, err = newAliyunAPI(credentials, t.cfg.Endpoint, "slb.aliyuncs.com", "2014-05-15")
+	case config.DeployAliyunALB:
+		t.api, err = newAliyunAPI(credentials, t.cfg.Endpoint, fmt.Sprintf("alb.%s.aliyuncs.com", t.region), "2020-06-16")
+	case config.DeployAliyunOSS:
+		t.oss = newOSSClient(credentials, t.cfg.Endpoint, t.region, t.cfg.Bucket)
+	default:
+		err = fmt.Errorf("不支持的阿里云部署目标类型: %s", typ)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Deploy 上传证书到 CAS 并绑定到云产品
+func (t *aliyunTarget) Deploy(ctx context.Context, req *Request) error {
+	cert, err := t.cas.Upload(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	switch t.typ {
+	case config.DeployAliyunCDN:
+		err = t.bindCDN(ctx, "SetCdnDomainSSLCertificate", cert)
+	case config.DeployAliyunDCDN:
+		err = t.bindCDN(ctx, "SetDcdnDomainSSLCertificate", cert)
+	case config.DeployAliyunSLB:
+		err = t.bindSLB(ctx, cert)
+	case config.DeployAliyunALB:
+		err = t.bindALB(ctx, cert)
+	case config.DeployAliyunOSS:
+		err = t.oss.PutCname(ctx, t.cfg.Domain, cert.regionID(t.cas.region))
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[阿里云] 证书 %s 已绑定到 %s", cert.Name, t.describe())
+	return nil
+}
+
+// describe 返回绑定位置的描述，用于日志
+func (t *aliyunTarget) describe() string {
+	switch t.typ {
+	case config.DeployAliyunSLB:
+		return fmt.Sprintf("SLB %s:%d", t.cfg.LoadBalancerID, t.cfg.ListenerPort)
+	case config.DeployAliyunALB:
+		return fmt.Sprintf("ALB 监听 %s", t.cfg.ListenerID)
+	case config.DeployAliyunOSS:
+		return fmt.Sprintf("OSS %s (%s)", t.cfg.Bucket, t.cfg.Domain)
+	case config.DeployAliyunDCDN:
+		return "DCDN " + t.cfg.Domain
+	default:
+		return "CDN " + t.cfg.Domain
+	}
+}
+
+// bindCDN 为 CDN 或 DCDN 加速域名开启 HTTPS 并使用 CAS 中的证书
+func (t *aliyunTarget) bindCDN(ctx context.Context, action string, cert *casCertificate) error {
+	return t.api.call(ctx, action, map[string]interface{}{
+		"DomainName":  t.cfg.Domain,
+		"SSLProtocol": "on",
+		"CertType":    "cas",
+		"CertId":      strconv.FormatInt(cert.ID, 10),
+		"CertName":    cert.Name,
+		"CertRegion":  t.cas.region,
+	}, nil)
+}
+
+// bindSLB 将 CAS 证书导入为 SLB 服务器证书（已导入时复用），并设置为 HTTPS 监听的证书
+func (t *aliyunTarget) bindSLB(ctx context.Context, cert *casCertificate) error {
+	serverCertID, err := t.slbServerCertificate(ctx, cert)
+	if err != nil {
+		return err
+	}
+	return t.api.call(ctx, "SetLoadBalancerHTTPSListenerAttribute", map[string]interface{}{
+		"RegionId":            t.region,
+		"LoadBalancerId":      t.cfg.LoadBalancerID,
+		"ListenerPort":        strconv.Itoa(t.cfg.ListenerPort),
+		"ServerCertificateId": serverCertID,
+	}, nil)
+}
+
+// slbServerCertificate 返回 CAS 证书对应的 SLB 服务器证书ID，未导入时导入
+func (t *aliyunTarget) slbServerCertificate(ctx context.Context, cert *casCertificate) (string, error) {
+	casID := strconv.FormatInt(cert.ID, 10)
+
+	var list struct {
+		ServerCertificates struct {
+			ServerCertificate []struct {
+				ServerCertificateId   string
+				AliCloudCertificateId string
+			}
+		}
+	}
+	err := t.api.call(ctx, "DescribeServerCertificates", map[string]interface{}{
+		"RegionId": t.region,
+	}, &list)
+	if err != nil {
+		return "", fmt.Errorf("查询SLB服务器证书失败: %w", err)
+	}
+	for _, item := range list.ServerCertificates.ServerCertificate {
+		if item.AliCloudCertificateId == casID {
+			return item.ServerCertificateId, nil
+		}
+	}
+
+	var resp struct {
+		ServerCertificateId string
+	}
+	err = t.api.call(ctx, "UploadServerCertificate", map[string]interface{}{
+		"RegionId":                    t.region,
+		"AliCloudCertificateId":       casID,
+		"AliCloudCertificateName":     cert.Name,
+		"AliCloudCertificateRegionId": t.cas.region,
+	}, &resp)
+	if err != nil {
+		return "", fmt.Errorf("导入SLB服务器证书失败: %w", err)
+	}
+	if resp.ServerCertificateId == "" {
+		return "", fmt.Errorf("导入SLB服务器证书失败: 响应中没有服务器证书ID")
+	}
+	return resp.ServerCertificateId, nil
+}
+
+// bindALB 更新 ALB HTTPS 监听的默认证书
+func (t *aliyunTarget) bindALB(ctx context.Context, cert *casCertificate) error {
+	return t.api.call(ctx, "UpdateListenerAttribute", map[string]interface{}{
+		"ListenerId": t.cfg.ListenerID,
+		"Certificates": []map[string]string{
+			{"CertificateId": cert.regionID(t.cas.region)},
+		},
+	}, nil)
+}
@@ -0,0 +1,128 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"ssl-manager/internal/config"
+)
+
+// maxOSSErrorSize OSS 错误响应的最大读取字节数
+const maxOSSErrorSize = 64 << 10
+
+// ossClient OSS Bucket 自定义域名 (CNAME) 管理客户端，使用 OSS V1 签名
+type ossClient struct {
+	client       *http.Client
+	url          string // 请求地址（不含路径）
+	host         string // Host 请求头: <bucket>.<endpoint>
+	bucket       string
+	accessKeyID  string
+	accessSecret string
+}
+
+// newOSSClient 创建 OSS 客户端，endpoint 为空时使用 oss-<region>.aliyuncs.com
+// endpoint 带 http:// 或 https:// 前缀时请求直接发送到该地址，Host 请求头仍为 <bucket>.<endpoint>（用于本地模拟服务）
+func newOSSClient(cfg *config.AliyunConfig, endpoint, region, bucket string) *ossClient {
+	c := &ossClient{
+		client:       &http.Client{},
+		bucket:       bucket,
+		accessKeyID:  cfg.AccessKeyID,
+		accessSecret: cfg.AccessKeySecret,
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("oss-%s.aliyuncs.com", region)
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	if scheme, host, ok := strings.Cut(endpoint, "://"); ok {
+		c.url = scheme + "://" + host
+		c.host = bucket + "." + host
+	} else {
+		c.host = bucket + "." + endpoint
+		c.url = "https://" + c.host
+	}
+	return c
+}
+
+// ossCnameConfiguration PutCname 请求体
+type ossCnameConfiguration struct {
+	XMLName xml.Name `xml:"BucketCnameConfiguration"`
+	Cname   struct {
+		Domain                   string
+		CertificateConfiguration struct {
+			CertId string
+			Force  bool
+		}
+	}
+}
+
+// ossError OSS 错误响应
+type ossError struct {
+	Code      string
+	Message   string
+	RequestId string
+}
+
+// PutCname 为 Bucket 的自定义域名绑定 CAS 证书，certID 为 <证书ID>-<地域> 格式
+// 域名需要已经绑定到 Bucket，Force 为 true 时覆盖原有证书
+func (c *ossClient) PutCname(ctx context.Context, domain, certID string) error {
+	var cfg ossCnameConfiguration
+	cfg.Cname.Domain = domain
+	cfg.Cname.CertificateConfiguration.CertId = certID
+	cfg.Cname.CertificateConfiguration.Force = true
+	body, err := xml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("生成请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/?cname&comp=add", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	sum := md5.Sum(body)
+	req.Host = c.host
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Authorization", c.sign(req, "/"+c.bucket+"/?cname&comp=add"))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 OSS 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxOSSErrorSize))
+	var ossErr ossError
+	if xml.Unmarshal(data, &ossErr) == nil && ossErr.Code != "" {
+		return fmt.Errorf("OSS 绑定证书失败: %s: %s (RequestId: %s)", ossErr.Code, ossErr.Message, ossErr.RequestId)
+	}
+	return fmt.Errorf("OSS 绑定证书失败: 状态码 %d", resp.StatusCode)
+}
+
+// sign 计算 OSS V1 签名，resource 为规范化资源（/<bucket>/<object>?<子资源>）
+func (c *ossClient) sign(req *http.Request, resource string) string {
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		req.Header.Get("Date"),
+		resource,
+	}, "\n")
+	mac := hmac.New(sha1.New, []byte(c.accessSecret))
+	mac.Write([]byte(stringToSign))
+	return "OSS " + c.accessKeyID + ":" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,194 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/storage"
+)
+
+// copyTarget 将证书文件复制到本地目录（如挂载的共享目录）
+type copyTarget struct {
+	dir      string            // 目标目录
+	files    map[string]string // 文件名 -> 目标路径，为空时复制所有证书文件到 dir
+	uid, gid int               // -1 表示不修改
+	mode     os.FileMode
+	keyMode  os.FileMode
+	dirMode  os.FileMode
+}
+
+// newCopyTarget 创建复制目标，目录和文件名模板在创建时渲染
+func newCopyTarget(domain string, cfg *config.LayoutConfig) (*copyTarget, error) {
+	t := &copyTarget{files: map[string]string{}}
+
+	var err error
+	if t.mode, t.keyMode, t.dirMode, err = cfg.GetModes(); err != nil {
+		return nil, err
+	}
+	if t.uid, t.gid, err = cfg.GetOwnership(); err != nil {
+		return nil, err
+	}
+
+	data := map[string]string{"Domain": domain}
+	if t.dir, err = renderTemplate(cfg.Dir, data); err != nil {
+		return nil, fmt.Errorf("生成目录失败: %w", err)
+	}
+	for name, value := range cfg.Files {
+		target, err := renderTemplate(value, data)
+		if err != nil {
+			return nil, fmt.Errorf("生成 %s 文件名失败: %w", name, err)
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(t.dir, target)
+		}
+		t.files[name] = target
+	}
+	return t, nil
+}
+
+// Deploy 复制证书文件，内容未变化的文件不会重写
+func (t *copyTarget) Deploy(ctx context.Context, req *Request) error {
+	files := t.files
+	if len(files) == 0 {
+		files = map[string]string{}
+		for name := range req.Paths.Files() {
+			files[name] = filepath.Join(t.dir, name)
+		}
+		for _, name := range []string{"cert.pem", "key.pem", "fullchain.pem"} {
+			files[name] = filepath.Join(t.dir, name)
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := readSource(req, name)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			log.Printf("  - 跳过 %s: 文件不存在", name)
+			continue
+		}
+		if err := t.write(files[name], data, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSource 读取要复制的文件内容，文件不存在时返回 nil
+// 证书、私钥和证书链直接使用证书内容（私钥可能只保存在远程存储或加密保存），其他文件从本地证书目录读取
+func readSource(req *Request, name string) ([]byte, error) {
+	cert := req.Certificate
+	switch name {
+	case "cert.pem":
+		return []byte(cert.Certificate), nil
+	case "key.pem":
+		if cert.PrivateKey == "" {
+			return nil, nil
+		}
+		return []byte(cert.PrivateKey), nil
+	case "fullchain.pem":
+		if cert.Chain != "" {
+			return []byte(cert.Chain), nil
+		}
+		return []byte(cert.Certificate), nil
+	}
+
+	path := req.Paths.Files()[name]
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+	}
+	return data, nil
+}
+
+// write 写入目标文件（先写临时文件再重命名）并设置权限和属主
+func (t *copyTarget) write(target string, data []byte, name string) error {
+	perm := t.mode
+	if storage.IsSecretFile(name) {
+		perm = t.keyMode
+	}
+
+	dir := filepath.Dir(target)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, t.dirMode); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+		if err := t.apply(dir, t.dirMode); err != nil {
+			return err
+		}
+	}
+
+	if existing, err := os.ReadFile(target); err != nil || !bytes.Equal(existing, data) {
+		tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".tmp-")
+		if err != nil {
+			return fmt.Errorf("创建临时文件失败: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := tmp.Write(data); err != nil {
+			tmp.Close()
+			return fmt.Errorf("写入 %s 失败: %w", target, err)
+		}
+		if err := tmp.Close(); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", target, err)
+		}
+		// 重命名前设置权限，私钥文件不会以默认权限出现在目标位置
+		if err := t.apply(tmp.Name(), perm); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), target); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", target, err)
+		}
+		log.Printf("  - 已复制: %s", target)
+		return nil
+	}
+	return t.apply(target, perm)
+}
+
+// apply 设置文件或目录的权限和属主
+func (t *copyTarget) apply(path string, mode os.FileMode) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("设置 %s 权限失败: %w", path, err)
+	}
+	if t.uid >= 0 || t.gid >= 0 {
+		if err := os.Chown(path, t.uid, t.gid); err != nil {
+			return fmt.Errorf("设置 %s 属主失败: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// renderTemplate 渲染目录或文件名模板（支持的变量: {{.Domain}}）
+func renderTemplate(text string, data map[string]string) (string, error) {
+	tmpl, err := template.New("deploy").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,163 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/provider"
+	"ssl-manager/internal/storage"
+)
+
+// 默认的重试等待时间和单次部署超时时间
+const (
+	defaultRetryDelay = 10 * time.Second
+	defaultTimeout    = 5 * time.Minute
+)
+
+// Request 一次部署的内容
+type Request struct {
+	Domain      string
+	Certificate *provider.Certificate // 证书、明文私钥、证书链和元数据
+	Paths       storage.Paths         // 本地证书文件路径（配置了文件布局时为发布后的路径）
+	Vars        map[string]string     // 与后置命令相同的变量
+}
+
+// Target 部署目标
+// 新增目标类型时实现该接口，并在 newTarget 中按配置类型创建
+type Target interface {
+	// Deploy 将证书部署到目标，失败时返回错误，由调用方按配置重试
+	Deploy(ctx context.Context, req *Request) error
+}
+
+// Result 单个部署目标的部署结果
+type Result struct {
+	Target   string // 目标名称
+	Type     string // 目标类型
+	Attempts int    // 尝试次数
+	Err      error  // 最后一次尝试的错误，成功时为 nil
+}
+
+// Deployer 域名的部署目标列表
+type Deployer struct {
+	domain  string
+	targets []*entry
+}
+
+// entry 部署目标及其重试配置
+type entry struct {
+	name       string
+	typ        string
+	target     Target
+	retries    int
+	retryDelay time.Duration
+	timeout    time.Duration
+}
+
+// New 根据域名的 deploy 配置创建部署目标列表，providers 为云平台凭证（云产品部署目标使用）
+func New(domain string, cfgs []config.DeployConfig, providers *config.ProvidersConfig) (*Deployer, error) {
+	d := &Deployer{domain: domain}
+	for i := range cfgs {
+		cfg := &cfgs[i]
+		target, err := newTarget(domain, cfg, providers)
+		if err != nil {
+			return nil, fmt.Errorf("创建部署目标 %s 失败: %w", cfg.GetName(i), err)
+		}
+
+		e := &entry{
+			name:       cfg.GetName(i),
+			typ:        cfg.Type,
+			target:     target,
+			retries:    cfg.GetRetries(),
+			retryDelay: defaultRetryDelay,
+			timeout:    defaultTimeout,
+		}
+		if cfg.RetryDelay > 0 {
+			e.retryDelay = time.Duration(cfg.RetryDelay) * time.Second
+		}
+		if cfg.Timeout > 0 {
+			e.timeout = time.Duration(cfg.Timeout) * time.Second
+		}
+		d.targets = append(d.targets, e)
+	}
+	return d, nil
+}
+
+// newTarget 按类型创建部署目标
+func newTarget(domain string, cfg *config.DeployConfig, providers *config.ProvidersConfig) (Target, error) {
+	switch cfg.Type {
+	case config.DeployCopy:
+		return newCopyTarget(domain, cfg.Copy)
+	case config.DeployExec:
+		return newExecTarget(cfg.Exec), nil
+	case config.DeployAliyunCDN, config.DeployAliyunDCDN, config.DeployAliyunSLB, config.DeployAliyunALB, config.DeployAliyunOSS:
+		return newAliyunTarget(domain, cfg.Type, cfg.Aliyun, providers)
+	default:
+		return nil, fmt.Errorf("不支持的部署目标类型: %s", cfg.Type)
+	}
+}
+
+// Names 返回所有部署目标的名称（按配置顺序）
+func (d *Deployer) Names() []string {
+	names := make([]string, 0, len(d.targets))
+	for _, e := range d.targets {
+		names = append(names, e.name)
+	}
+	return names
+}
+
+// Deploy 按配置顺序部署到各个目标，names 不为空时只部署指定名称的目标
+// 每个目标独立重试，一个目标失败不影响其他目标
+func (d *Deployer) Deploy(ctx context.Context, req *Request, names ...string) []Result {
+	selected := map[string]bool{}
+	for _, name := range names {
+		selected[name] = true
+	}
+
+	var results []Result
+	for _, e := range d.targets {
+		if len(names) > 0 && !selected[e.name] {
+			continue
+		}
+		if ctx.Err() != nil {
+			results = append(results, Result{Target: e.name, Type: e.typ, Err: ctx.Err()})
+			continue
+		}
+		results = append(results, e.deploy(ctx, req))
+	}
+	return results
+}
+
+// deploy 部署到单个目标，失败时按指数退避重试
+func (e *entry) deploy(ctx context.Context, req *Request) Result {
+	result := Result{Target: e.name, Type: e.typ}
+	delay := e.retryDelay
+
+	for {
+		result.Attempts++
+		log.Printf("部署到 %s (%s)...", e.name, e.typ)
+
+		attemptCtx, cancel := context.WithTimeout(ctx, e.timeout)
+		result.Err = e.target.Deploy(attemptCtx, req)
+		cancel()
+		if result.Err == nil {
+			log.Printf("部署到 %s 成功", e.name)
+			return result
+		}
+
+		if result.Attempts > e.retries {
+			log.Printf("部署到 %s 失败（共尝试 %d 次）: %v", e.name, result.Attempts, result.Err)
+			return result
+		}
+		log.Printf("部署到 %s 失败（第 %d 次）: %v，%s 后重试", e.name, result.Attempts, result.Err, delay)
+
+		select {
+		case <-ctx.Done():
+			return result
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,60 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"ssl-manager/internal/config"
+)
+
+// maxOutputSize 失败时错误信息中保留的命令输出字节数
+const maxOutputSize = 512
+
+// execTarget 执行命令的部署目标（如 scp 到远程主机、调用内部发布接口）
+type execTarget struct {
+	command string
+}
+
+// newExecTarget 创建执行命令的部署目标
+func newExecTarget(cfg *config.ExecDeployConfig) *execTarget {
+	return &execTarget{command: cfg.Command}
+}
+
+// Deploy 替换命令中的变量后通过 sh -c 执行，变量同时以环境变量传入，命令以非零状态退出时视为失败
+func (t *execTarget) Deploy(ctx context.Context, req *Request) error {
+	command := t.command
+	env := os.Environ()
+	for key, value := range req.Vars {
+		command = strings.ReplaceAll(command, "${"+key+"}", value)
+		env = append(env, key+"="+value)
+	}
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = env
+	cmd.Stdout = io.MultiWriter(os.Stdout, &output)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &output)
+	// 超时后 sh 被终止，但它启动的子进程可能仍持有输出管道，不再等待
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("执行命令超时: %w", ctx.Err())
+		}
+		out := strings.TrimSpace(output.String())
+		if len(out) > maxOutputSize {
+			out = "..." + out[len(out)-maxOutputSize:]
+		}
+		if out != "" {
+			return fmt.Errorf("执行命令失败: %w (输出: %s)", err, out)
+		}
+		return fmt.Errorf("执行命令失败: %w", err)
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"ssl-manager/internal/config"
+	"ssl-manager/internal/provider"
 )
 
 // EventType 事件类型
@@ -21,6 +22,10 @@ const (
 	EventCertRenewed    EventType = "cert_renewed"    // 证书申请/续期成功
 	EventCertFailed     EventType = "cert_failed"     // 证书申请失败
 	EventDNSValidationTimeout EventType = "dns_timeout" // DNS 验证超时
+	EventCertRevoked    EventType = "cert_revoked"    // 证书已吊销
+	EventDeployDrift    EventType = "deploy_drift"    // 线上端点仍在使用旧证书
+	EventCertInvalid    EventType = "cert_invalid"    // 下载的证书未通过校验
+	EventDeployFailed   EventType = "deploy_failed"   // 部署到部署目标失败
 )
 
 // EventData 事件数据
@@ -210,11 +215,21 @@ func (w *WebhookNotifier) NotifyCertExpiring(ctx context.Context, domain string,
 	return w.Notify(ctx, EventCertExpiring, domain, message, data)
 }
 
-// NotifyCertRenewed 通知证书申请/续期成功
-func (w *WebhookNotifier) NotifyCertRenewed(ctx context.Context, domain string, certID string) error {
-	message := fmt.Sprintf("证书申请/续期成功: %s", domain)
+// NotifyCertRenewed 通知证书申请/续期成功，附带新证书的元数据
+func (w *WebhookNotifier) NotifyCertRenewed(ctx context.Context, domain string, meta provider.Metadata, changed bool, previousSerial string) error {
+	message := fmt.Sprintf("证书申请/续期成功: %s (序列号: %s, 过期时间: %s)", domain, meta.Serial, meta.NotAfter.Format("2006-01-02"))
 	data := map[string]interface{}{
-		"cert_id": certID,
+		"cert_id":         meta.CertID,
+		"order_id":        meta.OrderID,
+		"serial":          meta.Serial,
+		"issuer":          meta.Issuer,
+		"sans":            meta.SANs,
+		"not_before":      meta.NotBefore.Format(time.RFC3339),
+		"not_after":       meta.NotAfter.Format(time.RFC3339),
+		"key_type":        meta.KeyType,
+		"fingerprint":     meta.Fingerprint,
+		"changed":         changed,
+		"previous_serial": previousSerial,
 	}
 	return w.Notify(ctx, EventCertRenewed, domain, message, data)
 }
@@ -237,6 +252,63 @@ func (w *WebhookNotifier) NotifyDNSValidationTimeout(ctx context.Context, domain
 	return w.Notify(ctx, EventDNSValidationTimeout, domain, message, data)
 }
 
+// NotifyCertRevoked 通知证书已吊销
+func (w *WebhookNotifier) NotifyCertRevoked(ctx context.Context, domain string, certID string, reason string) error {
+	message := fmt.Sprintf("证书已吊销: %s (证书ID: %s, 原因: %s)", domain, certID, reason)
+	data := map[string]interface{}{
+		"cert_id": certID,
+		"reason":  reason,
+	}
+	return w.Notify(ctx, EventCertRevoked, domain, message, data)
+}
+
+// NotifyOCSPRevoked 通知 OCSP 检查发现证书已被吊销（将自动重新签发）
+func (w *WebhookNotifier) NotifyOCSPRevoked(ctx context.Context, domain string, serial string, reason string, revokedAt time.Time) error {
+	message := fmt.Sprintf("OCSP 报告证书已吊销: %s (序列号: %s, 原因: %s)，正在重新签发", domain, serial, reason)
+	data := map[string]interface{}{
+		"serial":     serial,
+		"reason":     reason,
+		"revoked_at": revokedAt.Format(time.RFC3339),
+		"source":     "ocsp",
+	}
+	return w.Notify(ctx, EventCertRevoked, domain, message, data)
+}
+
+// NotifyDeployDrift 通知线上端点仍在使用与本地不一致的证书
+func (w *WebhookNotifier) NotifyDeployDrift(ctx context.Context, domain string, endpoint string, expectedSerial string, servedSerial string, servedNotAfter time.Time) error {
+	message := fmt.Sprintf("证书部署不一致: %s 的端点 %s 仍在使用旧证书 (线上序列号: %s, 本地序列号: %s)", domain, endpoint, servedSerial, expectedSerial)
+	data := map[string]interface{}{
+		"endpoint":         endpoint,
+		"expected_serial":  expectedSerial,
+		"served_serial":    servedSerial,
+		"served_not_after": servedNotAfter.Format(time.RFC3339),
+	}
+	return w.Notify(ctx, EventDeployDrift, domain, message, data)
+}
+
+// NotifyCertInvalid 通知下载的证书未通过校验（未保存和部署）
+func (w *WebhookNotifier) NotifyCertInvalid(ctx context.Context, domain string, serial string, reason string) error {
+	message := fmt.Sprintf("证书校验失败，未部署: %s (原因: %s)", domain, reason)
+	data := map[string]interface{}{
+		"serial": serial,
+		"reason": reason,
+	}
+	return w.Notify(ctx, EventCertInvalid, domain, message, data)
+}
+
+// NotifyDeployFailed 通知部署到部署目标失败（已用完重试次数）
+func (w *WebhookNotifier) NotifyDeployFailed(ctx context.Context, domain string, target string, targetType string, serial string, attempts int, reason string) error {
+	message := fmt.Sprintf("证书部署失败: %s 部署到 %s 失败 (尝试 %d 次, 原因: %s)", domain, target, attempts, reason)
+	data := map[string]interface{}{
+		"target":   target,
+		"type":     targetType,
+		"serial":   serial,
+		"attempts": attempts,
+		"reason":   reason,
+	}
+	return w.Notify(ctx, EventDeployFailed, domain, message, data)
+}
+
 // IsEnabled 检查是否启用
 func (w *WebhookNotifier) IsEnabled() bool {
 	return w != nil && w.config != nil && w.config.Enabled
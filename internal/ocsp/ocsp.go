@@ -0,0 +1,152 @@
+package ocsp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxResponseSize OCSP 响应的最大字节数
+const maxResponseSize = 1 << 20
+
+// ErrNoResponder 证书中没有 OCSP 服务地址
+var ErrNoResponder = errors.New("证书中没有 OCSP 服务地址")
+
+// 证书状态
+const (
+	StatusGood    = "good"
+	StatusRevoked = "revoked"
+	StatusUnknown = "unknown"
+)
+
+// Response OCSP 查询结果
+type Response struct {
+	Status     string    // 证书状态: good, revoked, unknown
+	RevokedAt  time.Time // 吊销时间（仅 revoked）
+	Reason     int       // 吊销原因代码 (RFC 5280)，仅 revoked
+	ThisUpdate time.Time // 响应生成时间
+	NextUpdate time.Time // 下次更新时间，为零表示响应方未提供
+	Raw        []byte    // DER 编码的原始响应，可直接用作 stapling 文件
+}
+
+// Client OCSP 查询客户端
+type Client struct {
+	client *http.Client
+}
+
+// NewClient 创建 OCSP 查询客户端，client 为 nil 时使用默认客户端
+func NewClient(client *http.Client) *Client {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{client: client}
+}
+
+// Query 向证书的 OCSP 服务查询证书状态，issuer 为签发者证书
+// 响应的签名会被校验，过期的响应视为错误
+func (c *Client) Query(ctx context.Context, leaf, issuer *x509.Certificate) (*Response, error) {
+	if len(leaf.OCSPServer) == 0 {
+		return nil, ErrNoResponder
+	}
+
+	request, err := ocsp.CreateRequest(leaf, issuer, &ocsp.RequestOptions{Hash: crypto.SHA1})
+	if err != nil {
+		return nil, fmt.Errorf("创建 OCSP 请求失败: %w", err)
+	}
+
+	var lastErr error
+	for _, server := range leaf.OCSPServer {
+		resp, err := c.post(ctx, server, request, leaf, issuer)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+// post 发送 OCSP 请求并解析响应
+func (c *Client) post(ctx context.Context, server string, request []byte, leaf, issuer *x509.Certificate) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(request))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 %s 失败: %w", server, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求 %s 返回错误状态码: %d", server, resp.StatusCode)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 的响应失败: %w", server, err)
+	}
+
+	parsed, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 的 OCSP 响应失败: %w", server, err)
+	}
+	if !parsed.NextUpdate.IsZero() && time.Now().After(parsed.NextUpdate) {
+		return nil, fmt.Errorf("%s 返回的 OCSP 响应已过期 (%s)", server, parsed.NextUpdate.Format("2006-01-02 15:04:05"))
+	}
+
+	result := &Response{
+		ThisUpdate: parsed.ThisUpdate,
+		NextUpdate: parsed.NextUpdate,
+		Raw:        raw,
+	}
+	switch parsed.Status {
+	case ocsp.Good:
+		result.Status = StatusGood
+	case ocsp.Revoked:
+		result.Status = StatusRevoked
+		result.RevokedAt = parsed.RevokedAt
+		result.Reason = parsed.RevocationReason
+	default:
+		result.Status = StatusUnknown
+	}
+	return result, nil
+}
+
+// ReasonString 返回吊销原因代码对应的名称
+func ReasonString(reason int) string {
+	switch reason {
+	case ocsp.Unspecified:
+		return "unspecified"
+	case ocsp.KeyCompromise:
+		return "keyCompromise"
+	case ocsp.CACompromise:
+		return "cACompromise"
+	case ocsp.AffiliationChanged:
+		return "affiliationChanged"
+	case ocsp.Superseded:
+		return "superseded"
+	case ocsp.CessationOfOperation:
+		return "cessationOfOperation"
+	case ocsp.CertificateHold:
+		return "certificateHold"
+	case ocsp.RemoveFromCRL:
+		return "removeFromCRL"
+	case ocsp.PrivilegeWithdrawn:
+		return "privilegeWithdrawn"
+	case ocsp.AACompromise:
+		return "aACompromise"
+	default:
+		return fmt.Sprintf("reason(%d)", reason)
+	}
+}
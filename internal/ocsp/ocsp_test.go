@@ -0,0 +1,209 @@
+package ocsp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// testCA 测试用 CA，同时作为 OCSP 响应方
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// issue 签发证书，ocspServers 为证书中的 OCSP 服务地址
+func (ca *testCA) issue(t *testing.T, serial int64, ocspServers ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		DNSNames:     []string{"www.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(12 * time.Hour),
+		OCSPServer:   ocspServers,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// responder 测试用 OCSP 响应方，按序列号返回 template 生成的响应
+type responder struct {
+	t        *testing.T
+	ca       *testCA
+	signer   *ecdsa.PrivateKey // 签名私钥，为空时使用 CA 私钥
+	template func(serial *big.Int) ocsp.Response
+}
+
+func (r *responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil || req.Header.Get("Content-Type") != "application/ocsp-request" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	request, err := ocsp.ParseRequest(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	template := r.template(request.SerialNumber)
+	template.SerialNumber = request.SerialNumber
+	signer := r.signer
+	if signer == nil {
+		signer = r.ca.key
+	}
+	resp, err := ocsp.CreateResponse(r.ca.cert, r.ca.cert, template, signer)
+	if err != nil {
+		r.t.Errorf("生成 OCSP 响应失败: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/ocsp-response")
+	w.Write(resp)
+}
+
+// serveResponder 启动 OCSP 响应方，返回服务地址
+func serveResponder(t *testing.T, r *responder) string {
+	t.Helper()
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestQueryGoodAndRevoked(t *testing.T) {
+	ca := newTestCA(t)
+	revokedAt := time.Now().Add(-30 * time.Minute).UTC().Truncate(time.Second)
+	url := serveResponder(t, &responder{t: t, ca: ca, template: func(serial *big.Int) ocsp.Response {
+		resp := ocsp.Response{
+			Status:     ocsp.Good,
+			ThisUpdate: time.Now().Add(-time.Minute),
+			NextUpdate: time.Now().Add(time.Hour),
+		}
+		if serial.Int64() == 2 {
+			resp.Status = ocsp.Revoked
+			resp.RevokedAt = revokedAt
+			resp.RevocationReason = ocsp.KeyCompromise
+		}
+		return resp
+	}})
+	client := NewClient(nil)
+
+	good, err := client.Query(context.Background(), ca.issue(t, 1, url), ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if good.Status != StatusGood || good.NextUpdate.IsZero() || len(good.Raw) == 0 {
+		t.Fatalf("正常证书的查询结果 = %+v", good)
+	}
+
+	revoked, err := client.Query(context.Background(), ca.issue(t, 2, url), ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked.Status != StatusRevoked || !revoked.RevokedAt.Equal(revokedAt) || revoked.Reason != ocsp.KeyCompromise {
+		t.Fatalf("吊销证书的查询结果 = %+v", revoked)
+	}
+	if got := ReasonString(revoked.Reason); got != "keyCompromise" {
+		t.Errorf("ReasonString = %q", got)
+	}
+}
+
+func TestQueryRejectsInvalidResponses(t *testing.T) {
+	ca := newTestCA(t)
+	fresh := func(*big.Int) ocsp.Response {
+		return ocsp.Response{Status: ocsp.Good, ThisUpdate: time.Now().Add(-time.Minute), NextUpdate: time.Now().Add(time.Hour)}
+	}
+
+	// 已过期的响应
+	expired := serveResponder(t, &responder{t: t, ca: ca, template: func(*big.Int) ocsp.Response {
+		return ocsp.Response{Status: ocsp.Good, ThisUpdate: time.Now().Add(-2 * time.Hour), NextUpdate: time.Now().Add(-time.Hour)}
+	}})
+	if _, err := NewClient(nil).Query(context.Background(), ca.issue(t, 1, expired), ca.cert); err == nil || !strings.Contains(err.Error(), "已过期") {
+		t.Errorf("过期的响应: err = %v", err)
+	}
+
+	// 签名与签发者不匹配的响应
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := serveResponder(t, &responder{t: t, ca: ca, signer: otherKey, template: fresh})
+	if _, err := NewClient(nil).Query(context.Background(), ca.issue(t, 1, forged), ca.cert); err == nil || !strings.Contains(err.Error(), "解析") {
+		t.Errorf("签名错误的响应: err = %v", err)
+	}
+
+	// 证书中没有 OCSP 服务地址
+	if _, err := NewClient(nil).Query(context.Background(), ca.issue(t, 1), ca.cert); !errors.Is(err, ErrNoResponder) {
+		t.Errorf("没有 OCSP 服务地址: err = %v", err)
+	}
+}
+
+func TestQueryFallsBackToNextResponder(t *testing.T) {
+	ca := newTestCA(t)
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+	url := serveResponder(t, &responder{t: t, ca: ca, template: func(*big.Int) ocsp.Response {
+		return ocsp.Response{Status: ocsp.Good, ThisUpdate: time.Now().Add(-time.Minute), NextUpdate: time.Now().Add(time.Hour)}
+	}})
+
+	resp, err := NewClient(nil).Query(context.Background(), ca.issue(t, 1, broken.URL, url), ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != StatusGood {
+		t.Fatalf("状态 = %s, 期望 good", resp.Status)
+	}
+}
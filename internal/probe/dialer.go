@@ -0,0 +1,121 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// contextDialer 支持 context 的拨号器
+type contextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// newDialer 根据代理配置创建拨号器，proxyURL 为空时直连
+func newDialer(proxyURL string, timeout time.Duration) (contextDialer, error) {
+	direct := &net.Dialer{Timeout: timeout}
+	if proxyURL == "" {
+		return direct, nil
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析代理地址失败: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &httpConnectDialer{proxy: u, forward: direct}, nil
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if u.User != nil {
+			password, _ := u.User.Password()
+			auth = &proxy.Auth{User: u.User.Username(), Password: password}
+		}
+		dialer, err := proxy.SOCKS5("tcp", u.Host, auth, direct)
+		if err != nil {
+			return nil, fmt.Errorf("创建 SOCKS5 代理失败: %w", err)
+		}
+		return dialer.(contextDialer), nil
+	default:
+		return nil, fmt.Errorf("不支持的代理协议: %s", u.Scheme)
+	}
+}
+
+// httpConnectDialer 通过 HTTP CONNECT 代理建立隧道
+type httpConnectDialer struct {
+	proxy   *url.URL
+	forward *net.Dialer
+}
+
+// DialContext 连接代理并发送 CONNECT 请求
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.forward.DialContext(ctx, network, d.proxy.Host)
+	if err != nil {
+		return nil, fmt.Errorf("连接代理失败: %w", err)
+	}
+
+	if d.proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxy.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("代理 TLS 握手失败: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if d.proxy.User != nil {
+		password, _ := d.proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("发送 CONNECT 请求失败: %w", err)
+	}
+
+	// 使用单字节缓冲读取响应，避免吞掉隧道建立后的数据
+	resp, err := http.ReadResponse(bufio.NewReaderSize(&byteReader{conn}, 16), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("读取 CONNECT 响应失败: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("代理拒绝 CONNECT 请求: %s", resp.Status)
+	}
+
+	return conn, nil
+}
+
+// byteReader 每次只读取一个字节的 Reader
+type byteReader struct {
+	conn net.Conn
+}
+
+func (r *byteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return r.conn.Read(p)
+}
@@ -0,0 +1,175 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"ssl-manager/internal/config"
+)
+
+// DefaultTimeout 默认连接超时
+const DefaultTimeout = 10 * time.Second
+
+// STARTTLS 协议默认端口
+var defaultPorts = map[string]int{
+	"":         443,
+	"smtp":     25,
+	"imap":     143,
+	"pop3":     110,
+	"postgres": 5432,
+}
+
+// Result 单个端点的检查结果
+type Result struct {
+	Endpoint     string              // 端点描述，如 mail.example.com:25 (1.2.3.4) [starttls=smtp]
+	Address      string              // 实际连接的地址
+	SNI          string              // 使用的 SNI
+	Certificates []*x509.Certificate // 服务端返回的证书链
+	Err          error               // 检查失败的错误
+}
+
+// Leaf 返回服务端证书，检查失败时返回 nil
+func (r *Result) Leaf() *x509.Certificate {
+	if len(r.Certificates) == 0 {
+		return nil
+	}
+	return r.Certificates[0]
+}
+
+// Domains 返回服务端证书覆盖的域名列表（CN + SANs）
+func (r *Result) Domains() []string {
+	leaf := r.Leaf()
+	if leaf == nil {
+		return nil
+	}
+	var domains []string
+	if leaf.Subject.CommonName != "" {
+		domains = append(domains, leaf.Subject.CommonName)
+	}
+	return append(domains, leaf.DNSNames...)
+}
+
+// Probe 按端点配置检查线上证书，每个目标地址返回一个结果
+func Probe(ctx context.Context, domain string, cfg *config.ProbeConfig) []*Result {
+	host := cfg.Host
+	if host == "" {
+		host = domain
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = defaultPorts[cfg.StartTLS]
+	}
+	sni := cfg.SNI
+	if sni == "" {
+		sni = domain
+	}
+	timeout := DefaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	endpoint := net.JoinHostPort(host, strconv.Itoa(port))
+
+	dialer, err := newDialer(cfg.Proxy, timeout)
+	if err != nil {
+		return []*Result{{Endpoint: endpoint, SNI: sni, Err: err}}
+	}
+
+	targets, err := resolveTargets(ctx, host, cfg, timeout)
+	if err != nil {
+		return []*Result{{Endpoint: endpoint, SNI: sni, Err: err}}
+	}
+
+	var results []*Result
+	for _, target := range targets {
+		address := net.JoinHostPort(target, strconv.Itoa(port))
+		result := &Result{
+			Endpoint: describeEndpoint(endpoint, target, host, cfg.StartTLS),
+			Address:  address,
+			SNI:      sni,
+		}
+		result.Certificates, result.Err = probeAddress(ctx, dialer, address, sni, cfg.StartTLS, timeout)
+		results = append(results, result)
+	}
+	return results
+}
+
+// resolveTargets 确定需要连接的目标地址列表
+func resolveTargets(ctx context.Context, host string, cfg *config.ProbeConfig, timeout time.Duration) ([]string, error) {
+	if len(cfg.IPs) > 0 {
+		return cfg.IPs, nil
+	}
+	if !cfg.ResolveAll || net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%s 没有 A/AAAA 记录", host)
+	}
+
+	targets := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		targets = append(targets, addr.IP.String())
+	}
+	return targets, nil
+}
+
+// probeAddress 连接单个地址，完成 STARTTLS 协商和 TLS 握手后返回证书链
+func probeAddress(ctx context.Context, dialer contextDialer, address, sni, startTLS string, timeout time.Duration) ([]*x509.Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	// 握手和协议交互同样受超时限制，避免服务端不响应时挂起
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	if startTLS != "" {
+		if err := negotiateStartTLS(conn, startTLS); err != nil {
+			return nil, fmt.Errorf("STARTTLS 协商失败: %w", err)
+		}
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         sni,
+		InsecureSkipVerify: true,
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("TLS 握手失败: %w", err)
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("未找到证书")
+	}
+	return certs, nil
+}
+
+// describeEndpoint 生成端点描述
+func describeEndpoint(endpoint, target, host, startTLS string) string {
+	desc := endpoint
+	if target != host {
+		desc += " (" + target + ")"
+	}
+	if startTLS != "" {
+		desc += " [starttls=" + startTLS + "]"
+	}
+	return desc
+}
@@ -0,0 +1,351 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ssl-manager/internal/config"
+)
+
+// testCertificate 生成自签名证书
+func testCertificate(t *testing.T, domain string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// serveTLS 启动 TLS 服务，negotiate 不为空时先在明文连接上完成 STARTTLS 协商，返回监听地址
+func serveTLS(t *testing.T, cert tls.Certificate, negotiate func(conn net.Conn, reader *bufio.Reader) error) (string, int) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if negotiate != nil {
+					if err := negotiate(conn, bufio.NewReader(conn)); err != nil {
+						return
+					}
+				}
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+				if err := tlsConn.Handshake(); err == nil {
+					io.Copy(io.Discard, tlsConn)
+				}
+			}()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	return host, portNum
+}
+
+// expectLine 读取一行并检查内容
+func expectLine(reader *bufio.Reader, want string) error {
+	line, err := readLine(reader)
+	if err != nil {
+		return err
+	}
+	if line != want {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func TestProbeStartTLS(t *testing.T) {
+	cert := testCertificate(t, "mail.example.com")
+	servers := map[string]func(conn net.Conn, reader *bufio.Reader) error{
+		"smtp": func(conn net.Conn, reader *bufio.Reader) error {
+			io.WriteString(conn, "220 mail.example.com ESMTP\r\n")
+			if err := expectLine(reader, "EHLO ssl-manager"); err != nil {
+				return err
+			}
+			io.WriteString(conn, "250-mail.example.com\r\n250-SIZE 10240000\r\n250 STARTTLS\r\n")
+			if err := expectLine(reader, "STARTTLS"); err != nil {
+				return err
+			}
+			_, err := io.WriteString(conn, "220 Ready to start TLS\r\n")
+			return err
+		},
+		"imap": func(conn net.Conn, reader *bufio.Reader) error {
+			io.WriteString(conn, "* OK IMAP4rev1 ready\r\n")
+			if err := expectLine(reader, "a001 STARTTLS"); err != nil {
+				return err
+			}
+			_, err := io.WriteString(conn, "* CAPABILITY IMAP4rev1\r\na001 OK Begin TLS negotiation now\r\n")
+			return err
+		},
+		"pop3": func(conn net.Conn, reader *bufio.Reader) error {
+			io.WriteString(conn, "+OK POP3 ready\r\n")
+			if err := expectLine(reader, "STLS"); err != nil {
+				return err
+			}
+			_, err := io.WriteString(conn, "+OK Begin TLS negotiation\r\n")
+			return err
+		},
+		"postgres": func(conn net.Conn, reader *bufio.Reader) error {
+			request := make([]byte, 8)
+			if _, err := io.ReadFull(reader, request); err != nil {
+				return err
+			}
+			if binary.BigEndian.Uint32(request[4:8]) != postgresSSLRequestCode {
+				return io.ErrUnexpectedEOF
+			}
+			_, err := conn.Write([]byte{'S'})
+			return err
+		},
+	}
+
+	for protocol, negotiate := range servers {
+		t.Run(protocol, func(t *testing.T) {
+			host, port := serveTLS(t, cert, negotiate)
+			results := Probe(context.Background(), "mail.example.com", &config.ProbeConfig{
+				Host: host, Port: port, StartTLS: protocol, Timeout: 5,
+			})
+			if len(results) != 1 {
+				t.Fatalf("结果数 = %d, 期望 1", len(results))
+			}
+			result := results[0]
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			if leaf := result.Leaf(); leaf == nil || leaf.Subject.CommonName != "mail.example.com" {
+				t.Fatalf("服务端证书 = %v", leaf)
+			}
+			if !strings.Contains(result.Endpoint, "[starttls="+protocol+"]") {
+				t.Errorf("端点描述 = %q", result.Endpoint)
+			}
+		})
+	}
+}
+
+func TestProbeStartTLSRefused(t *testing.T) {
+	cert := testCertificate(t, "mail.example.com")
+	host, port := serveTLS(t, cert, func(conn net.Conn, reader *bufio.Reader) error {
+		io.WriteString(conn, "220 mail.example.com ESMTP\r\n")
+		readLine(reader)
+		io.WriteString(conn, "250 mail.example.com\r\n")
+		readLine(reader)
+		io.WriteString(conn, "454 TLS not available\r\n")
+		return io.EOF
+	})
+
+	results := Probe(context.Background(), "mail.example.com", &config.ProbeConfig{
+		Host: host, Port: port, StartTLS: "smtp", Timeout: 5,
+	})
+	if len(results) != 1 || results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "STARTTLS 协商失败") {
+		t.Fatalf("结果 = %+v", results[0])
+	}
+}
+
+// connectProxy 模拟 HTTP CONNECT 代理，要求 Basic 认证，记录收到的 CONNECT 目标
+type connectProxy struct {
+	mu      sync.Mutex
+	targets []string
+}
+
+func (p *connectProxy) serve(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.handle(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func (p *connectProxy) handle(conn net.Conn) {
+	defer conn.Close()
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || req.Method != http.MethodConnect {
+		return
+	}
+	p.mu.Lock()
+	p.targets = append(p.targets, req.Host)
+	p.mu.Unlock()
+
+	if req.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:secret")) {
+		io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+		return
+	}
+	backend, err := net.Dial("tcp", req.Host)
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer backend.Close()
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	pipe(conn, backend)
+}
+
+// pipe 双向转发数据，任一方向结束后返回
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(a, b); done <- struct{}{} }()
+	go func() { io.Copy(b, a); done <- struct{}{} }()
+	<-done
+}
+
+func TestProbeHTTPProxy(t *testing.T) {
+	host, port := serveTLS(t, testCertificate(t, "www.example.com"), nil)
+	proxy := &connectProxy{}
+	proxyAddr := proxy.serve(t)
+
+	results := Probe(context.Background(), "www.example.com", &config.ProbeConfig{
+		Host: host, Port: port, Timeout: 5, Proxy: "http://user:secret@" + proxyAddr,
+	})
+	if results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	if leaf := results[0].Leaf(); leaf.Subject.CommonName != "www.example.com" {
+		t.Fatalf("服务端证书 CN = %s", leaf.Subject.CommonName)
+	}
+	if want := net.JoinHostPort(host, strconv.Itoa(port)); len(proxy.targets) != 1 || proxy.targets[0] != want {
+		t.Fatalf("CONNECT 目标 = %v, 期望 %s", proxy.targets, want)
+	}
+
+	// 认证失败时返回代理的拒绝状态
+	results = Probe(context.Background(), "www.example.com", &config.ProbeConfig{
+		Host: host, Port: port, Timeout: 5, Proxy: "http://user:wrong@" + proxyAddr,
+	})
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "407") {
+		t.Fatalf("错误 = %v, 期望代理拒绝 407", results[0].Err)
+	}
+}
+
+// serveSOCKS5 启动无认证的 SOCKS5 代理（只支持 CONNECT 到 IPv4 地址或域名）
+func serveSOCKS5(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				// 协商认证方式：版本、方法数、方法列表，回复无需认证
+				header := make([]byte, 2)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+					return
+				}
+				conn.Write([]byte{5, 0})
+
+				// CONNECT 请求：版本、命令、保留、地址类型、地址、端口
+				request := make([]byte, 4)
+				if _, err := io.ReadFull(conn, request); err != nil {
+					return
+				}
+				var host string
+				switch request[3] {
+				case 1:
+					ip := make([]byte, 4)
+					io.ReadFull(conn, ip)
+					host = net.IP(ip).String()
+				case 3:
+					length := make([]byte, 1)
+					io.ReadFull(conn, length)
+					name := make([]byte, length[0])
+					io.ReadFull(conn, name)
+					host = string(name)
+				default:
+					return
+				}
+				portBytes := make([]byte, 2)
+				io.ReadFull(conn, portBytes)
+				address := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(portBytes))))
+
+				backend, err := net.Dial("tcp", address)
+				if err != nil {
+					conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer backend.Close()
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				pipe(conn, backend)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestProbeSOCKS5Proxy(t *testing.T) {
+	host, port := serveTLS(t, testCertificate(t, "www.example.com"), nil)
+	proxyAddr := serveSOCKS5(t)
+
+	results := Probe(context.Background(), "www.example.com", &config.ProbeConfig{
+		Host: host, Port: port, Timeout: 5, Proxy: "socks5://" + proxyAddr,
+	})
+	if results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	if leaf := results[0].Leaf(); leaf.Subject.CommonName != "www.example.com" {
+		t.Fatalf("服务端证书 CN = %s", leaf.Subject.CommonName)
+	}
+}
+
+func TestProbeUnsupportedProxy(t *testing.T) {
+	results := Probe(context.Background(), "www.example.com", &config.ProbeConfig{Proxy: "ftp://127.0.0.1:21"})
+	if len(results) != 1 || results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "不支持的代理协议") {
+		t.Fatalf("结果 = %+v", results[0])
+	}
+}
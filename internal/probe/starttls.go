@@ -0,0 +1,145 @@
+package probe
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// postgresSSLRequestCode PostgreSQL SSLRequest 消息代码
+const postgresSSLRequestCode = 80877103
+
+// negotiateStartTLS 在明文连接上完成 STARTTLS 协商，返回后即可开始 TLS 握手
+func negotiateStartTLS(conn net.Conn, protocol string) error {
+	// 服务端在收到 STARTTLS 响应后等待 ClientHello，不会有多余数据，
+	// 因此这里的缓冲不会吞掉 TLS 握手数据
+	reader := bufio.NewReader(conn)
+
+	switch protocol {
+	case "smtp":
+		return startTLSSMTP(conn, reader)
+	case "imap":
+		return startTLSIMAP(conn, reader)
+	case "pop3":
+		return startTLSPOP3(conn, reader)
+	case "postgres":
+		return startTLSPostgres(conn, reader)
+	default:
+		return fmt.Errorf("不支持的 STARTTLS 协议: %s", protocol)
+	}
+}
+
+// startTLSSMTP SMTP: 问候 220 -> EHLO -> STARTTLS -> 220
+func startTLSSMTP(conn net.Conn, reader *bufio.Reader) error {
+	if err := readSMTPReply(reader, "220"); err != nil {
+		return fmt.Errorf("读取问候失败: %w", err)
+	}
+	if _, err := io.WriteString(conn, "EHLO ssl-manager\r\n"); err != nil {
+		return err
+	}
+	if err := readSMTPReply(reader, "250"); err != nil {
+		return fmt.Errorf("EHLO 失败: %w", err)
+	}
+	if _, err := io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+		return err
+	}
+	return readSMTPReply(reader, "220")
+}
+
+// readSMTPReply 读取 SMTP 多行响应并检查状态码
+func readSMTPReply(reader *bufio.Reader, code string) error {
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, code) {
+			return fmt.Errorf("意外的响应: %s", line)
+		}
+		// "250-xxx" 表示后续还有响应行，"250 xxx" 为最后一行
+		if len(line) == len(code) || line[len(code)] == ' ' {
+			return nil
+		}
+	}
+}
+
+// startTLSIMAP IMAP: 问候 * OK -> a001 STARTTLS -> a001 OK
+func startTLSIMAP(conn net.Conn, reader *bufio.Reader) error {
+	line, err := readLine(reader)
+	if err != nil {
+		return fmt.Errorf("读取问候失败: %w", err)
+	}
+	if !strings.HasPrefix(line, "* OK") {
+		return fmt.Errorf("意外的问候: %s", line)
+	}
+	if _, err := io.WriteString(conn, "a001 STARTTLS\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			return err
+		}
+		// 跳过未标记的响应
+		if strings.HasPrefix(line, "* ") {
+			continue
+		}
+		if strings.HasPrefix(line, "a001 OK") {
+			return nil
+		}
+		return fmt.Errorf("意外的响应: %s", line)
+	}
+}
+
+// startTLSPOP3 POP3: 问候 +OK -> STLS -> +OK
+func startTLSPOP3(conn net.Conn, reader *bufio.Reader) error {
+	line, err := readLine(reader)
+	if err != nil {
+		return fmt.Errorf("读取问候失败: %w", err)
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("意外的问候: %s", line)
+	}
+	if _, err := io.WriteString(conn, "STLS\r\n"); err != nil {
+		return err
+	}
+	line, err = readLine(reader)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("意外的响应: %s", line)
+	}
+	return nil
+}
+
+// startTLSPostgres PostgreSQL: 发送 SSLRequest，服务端回复 S 表示支持 TLS
+func startTLSPostgres(conn net.Conn, reader *bufio.Reader) error {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], postgresSSLRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	reply, err := reader.ReadByte()
+	if err != nil {
+		return err
+	}
+	if reply != 'S' {
+		return fmt.Errorf("服务端不支持 TLS (响应: %q)", reply)
+	}
+	return nil
+}
+
+// readLine 读取一行并去掉行尾换行符
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -2,8 +2,11 @@ package aliyun
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,15 +20,26 @@ import (
 
 // CertProvider 阿里云证书提供商
 type CertProvider struct {
-	client *cas.Client
+	client    *cas.Client
+	certCache provider.CertListCache
 }
 
 // NewCertProvider 创建阿里云证书提供商
 func NewCertProvider(cfg *config.AliyunConfig) (*CertProvider, error) {
+	return newCertProvider(cfg, "cas.aliyuncs.com")
+}
+
+// newCertProvider 创建使用指定接入地址的证书提供商，接入地址带 http:// 前缀时使用 HTTP（用于本地模拟服务）
+func newCertProvider(cfg *config.AliyunConfig, endpoint string) (*CertProvider, error) {
+	protocol := "HTTPS"
+	if strings.HasPrefix(endpoint, "http://") {
+		protocol = "HTTP"
+	}
 	clientConfig := &openapi.Config{
 		AccessKeyId:     tea.String(cfg.AccessKeyID),
 		AccessKeySecret: tea.String(cfg.AccessKeySecret),
-		Endpoint:        tea.String("cas.aliyuncs.com"),
+		Endpoint:        tea.String(strings.TrimPrefix(strings.TrimPrefix(endpoint, "http://"), "https://")),
+		Protocol:        tea.String(protocol),
 	}
 
 	client, err := cas.NewClient(clientConfig)
@@ -62,6 +76,106 @@ func (p *CertProvider) ApplyCertificate(ctx context.Context, domain string) (str
 	return orderID, nil
 }
 
+// ListPendingOrders 列出域名进行中的订单
+func (p *CertProvider) ListPendingOrders(ctx context.Context, domain string) ([]*provider.OrderInfo, error) {
+	orders, err := p.listOrders(&cas.ListUserCertificateOrderRequest{
+		OrderType: tea.String("CPACK"),
+		Status:    tea.String("CHECKING"),
+		Keyword:   tea.String(domain),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keyword 为模糊匹配，需要精确过滤
+	var pending []*provider.OrderInfo
+	for _, order := range orders {
+		if order.Domain == domain {
+			pending = append(pending, order)
+		}
+	}
+
+	return pending, nil
+}
+
+// ListOrders 列出所有证书订单
+func (p *CertProvider) ListOrders(ctx context.Context) ([]*provider.OrderInfo, error) {
+	return p.listOrders(&cas.ListUserCertificateOrderRequest{
+		OrderType: tea.String("CPACK"),
+	})
+}
+
+// listOrders 查询证书订单并转换为统一格式
+func (p *CertProvider) listOrders(request *cas.ListUserCertificateOrderRequest) ([]*provider.OrderInfo, error) {
+	list, err := p.listUserCertificateOrders(request)
+	if err != nil {
+		return nil, fmt.Errorf("获取订单列表失败: %w", err)
+	}
+
+	var orders []*provider.OrderInfo
+	for _, order := range list {
+		domain := tea.StringValue(order.Domain)
+		if domain == "" {
+			domain = tea.StringValue(order.CommonName)
+		}
+
+		var createdAt time.Time
+		if buyDate := tea.Int64Value(order.BuyDate); buyDate > 0 {
+			createdAt = time.UnixMilli(buyDate)
+		}
+
+		orders = append(orders, &provider.OrderInfo{
+			OrderID:   fmt.Sprintf("%d", tea.Int64Value(order.OrderId)),
+			Domain:    domain,
+			Status:    mapAliyunOrderStatus(tea.StringValue(order.Status)),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+
+	return orders, nil
+}
+
+// mapAliyunOrderStatus 映射阿里云订单列表状态到统一状态
+func mapAliyunOrderStatus(status string) string {
+	switch status {
+	case "PAYED", "NOTACTIVATED":
+		return "pending"
+	case "CHECKING":
+		return "domain_verify"
+	case "ISSUED", "WILLEXPIRED":
+		return "certificate"
+	case "CHECKED_FAIL":
+		return "failed"
+	case "EXPIRED":
+		return "expired"
+	case "REVOKED":
+		return "revoked"
+	default:
+		return strings.ToLower(status)
+	}
+}
+
+// CancelOrder 取消证书订单
+func (p *CertProvider) CancelOrder(ctx context.Context, orderID string) error {
+	var orderId int64
+	fmt.Sscanf(orderID, "%d", &orderId)
+
+	request := &cas.CancelOrderRequestRequest{
+		OrderId: tea.Int64(orderId),
+	}
+
+	if _, err := p.client.CancelOrderRequest(request); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+
+	log.Printf("[阿里云] 订单 %s 已取消", orderID)
+	return nil
+}
+
 // GetCertificateStatus 获取证书状态
 func (p *CertProvider) GetCertificateStatus(ctx context.Context, orderID string) (*provider.CertificateStatus, error) {
 	var orderId int64
@@ -80,6 +194,9 @@ func (p *CertProvider) GetCertificateStatus(ctx context.Context, orderID string)
 		if err == nil {
 			break
 		}
+		if isNotFound(err) {
+			return nil, fmt.Errorf("获取证书状态失败: %w (%w)", provider.ErrOrderNotFound, err)
+		}
 		lastErr = err
 		log.Printf("[阿里云] 获取证书状态失败 (重试 %d/3): %v", retry+1, err)
 		time.Sleep(time.Duration(retry+1) * 5 * time.Second)
@@ -144,29 +261,50 @@ func (p *CertProvider) DownloadCertificate(ctx context.Context, orderID string)
 		Certificate: certificate,
 		PrivateKey:  privateKey,
 		Chain:       certificate, // 阿里云返回的证书已包含证书链
+		Metadata:    provider.Metadata{OrderID: orderID},
 	}, nil
 }
 
 // ListCertificates 列出已签发的证书
 func (p *CertProvider) ListCertificates(ctx context.Context) ([]*provider.CertificateInfo, error) {
+	return p.certCache.Get(ctx, func(ctx context.Context) ([]*provider.CertificateInfo, error) {
+		return p.listCertificates("ISSUED", "issued")
+	})
+}
+
+// ResetCache 清空证书列表缓存
+func (p *CertProvider) ResetCache() {
+	p.certCache.Reset()
+}
+
+// ListExpiredCertificates 列出已过期的证书
+func (p *CertProvider) ListExpiredCertificates(ctx context.Context) ([]*provider.CertificateInfo, error) {
+	return p.listCertificates("EXPIRED", "expired")
+}
+
+// listCertificates 按阿里云状态查询证书列表，并标记为统一状态
+func (p *CertProvider) listCertificates(aliyunStatus, status string) ([]*provider.CertificateInfo, error) {
 	request := &cas.ListUserCertificateOrderRequest{
 		OrderType: tea.String("CERT"),
-		Status:    tea.String("ISSUED"),
+		Status:    tea.String(aliyunStatus),
 	}
 
-	response, err := p.client.ListUserCertificateOrder(request)
+	list, err := p.listUserCertificateOrders(request)
 	if err != nil {
 		return nil, fmt.Errorf("获取证书列表失败: %w", err)
 	}
 
 	var certs []*provider.CertificateInfo
-	for _, cert := range response.Body.CertificateOrderList {
+	for _, cert := range list {
 		domain := tea.StringValue(cert.CommonName)
 		if domain == "" {
 			domain = tea.StringValue(cert.Domain)
 		}
 
-		var notAfter time.Time
+		var notBefore, notAfter time.Time
+		if startTime := tea.Int64Value(cert.CertStartTime); startTime > 0 {
+			notBefore = time.UnixMilli(startTime)
+		}
 		if endTime := tea.Int64Value(cert.CertEndTime); endTime > 0 {
 			notAfter = time.UnixMilli(endTime)
 		}
@@ -177,18 +315,46 @@ func (p *CertProvider) ListCertificates(ctx context.Context) ([]*provider.Certif
 		}
 
 		certs = append(certs, &provider.CertificateInfo{
-			CertID:   fmt.Sprintf("%d", tea.Int64Value(cert.CertificateId)),
-			OrderID:  fmt.Sprintf("%d", tea.Int64Value(cert.OrderId)),
-			Domain:   domain,
-			Sans:     sans,
-			NotAfter: notAfter,
-			Status:   "issued",
+			CertID:    fmt.Sprintf("%d", tea.Int64Value(cert.CertificateId)),
+			OrderID:   fmt.Sprintf("%d", tea.Int64Value(cert.OrderId)),
+			Domain:    domain,
+			Sans:      sans,
+			NotBefore: notBefore,
+			NotAfter:  notAfter,
+			Status:    status,
 		})
 	}
 
 	return certs, nil
 }
 
+// listUserCertificateOrders 分页查询证书/订单列表，返回所有页的结果
+func (p *CertProvider) listUserCertificateOrders(request *cas.ListUserCertificateOrderRequest) ([]*cas.ListUserCertificateOrderResponseBodyCertificateOrderList, error) {
+	const pageSize = 100
+
+	var all []*cas.ListUserCertificateOrderResponseBodyCertificateOrderList
+	request.ShowSize = tea.Int64(pageSize)
+	for page := int64(1); ; page++ {
+		request.CurrentPage = tea.Int64(page)
+
+		response, err := p.client.ListUserCertificateOrder(request)
+		if err != nil {
+			return nil, err
+		}
+
+		list := response.Body.CertificateOrderList
+		all = append(all, list...)
+
+		// 已取完所有记录或当前页不满时结束
+		total := tea.Int64Value(response.Body.TotalCount)
+		if len(list) < pageSize || (total > 0 && int64(len(all)) >= total) {
+			break
+		}
+	}
+
+	return all, nil
+}
+
 // FindValidCertificate 查找域名的有效证书
 func (p *CertProvider) FindValidCertificate(ctx context.Context, domain string, minDays int) (*provider.CertificateInfo, error) {
 	certs, err := p.ListCertificates(ctx)
@@ -244,9 +410,109 @@ func (p *CertProvider) GetCertificateDetail(ctx context.Context, certID string)
 		Certificate: certificate,
 		PrivateKey:  privateKey,
 		Chain:       certificate,
+		Metadata:    provider.Metadata{CertID: certID},
 	}, nil
 }
 
+// ListBoundResources 列出证书绑定的云资源
+func (p *CertProvider) ListBoundResources(ctx context.Context, certID string) ([]string, error) {
+	var certId int64
+	fmt.Sscanf(certID, "%d", &certId)
+
+	request := &cas.ListCloudResourcesRequest{
+		CertIds: []*int64{tea.Int64(certId)},
+	}
+
+	response, err := p.client.ListCloudResources(request)
+	if err != nil {
+		return nil, fmt.Errorf("获取证书绑定资源失败: %w", err)
+	}
+
+	var resources []string
+	for _, resource := range response.Body.Data {
+		if tea.Int64Value(resource.CertId) != certId {
+			continue
+		}
+		name := tea.StringValue(resource.Domain)
+		if name == "" {
+			name = tea.StringValue(resource.InstanceId)
+		}
+		resources = append(resources, fmt.Sprintf("%s:%s", tea.StringValue(resource.CloudProduct), name))
+	}
+
+	return resources, nil
+}
+
+// DeleteCertificate 删除证书
+func (p *CertProvider) DeleteCertificate(ctx context.Context, certID string) error {
+	var certId int64
+	fmt.Sscanf(certID, "%d", &certId)
+
+	request := &cas.DeleteUserCertificateRequest{
+		CertId: tea.Int64(certId),
+	}
+
+	if _, err := p.client.DeleteUserCertificate(request); err != nil {
+		return fmt.Errorf("删除证书失败: %w", err)
+	}
+	p.certCache.Reset()
+
+	log.Printf("[阿里云] 证书 %s 已删除", certID)
+	return nil
+}
+
+// RevokeCertificate 吊销证书
+// 阿里云按订单吊销：通过证书列表找到证书对应的订单，吊销已签发的证书并取消订单，不需要额外的DNS验证
+// 阿里云不支持指定吊销原因，reason 只用于本地记录
+func (p *CertProvider) RevokeCertificate(ctx context.Context, certID, reason string) ([]*provider.DNSRecord, error) {
+	orderID, err := p.findOrderID(ctx, certID)
+	if err != nil {
+		return nil, err
+	}
+
+	var orderId int64
+	fmt.Sscanf(orderID, "%d", &orderId)
+	request := &cas.CancelCertificateForPackageRequestRequest{
+		OrderId: tea.Int64(orderId),
+	}
+	if _, err := p.client.CancelCertificateForPackageRequest(request); err != nil {
+		return nil, fmt.Errorf("吊销证书失败: %w", err)
+	}
+
+	p.certCache.Reset()
+	log.Printf("[阿里云] 证书 %s (订单ID: %s) 已吊销", certID, orderID)
+	return nil, nil
+}
+
+// findOrderID 查找证书对应的订单ID，上传的证书没有订单
+func (p *CertProvider) findOrderID(ctx context.Context, certID string) (string, error) {
+	certs, err := p.ListCertificates(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, cert := range certs {
+		if cert.CertID != certID {
+			continue
+		}
+		if cert.OrderID == "" || cert.OrderID == "0" {
+			return "", fmt.Errorf("证书 %s 没有对应的订单，只能吊销通过阿里云申请的证书", certID)
+		}
+		return cert.OrderID, nil
+	}
+	return "", fmt.Errorf("未找到已签发的证书 %s", certID)
+}
+
+// isNotFound 检查阿里云返回的错误是否表示资源不存在
+func isNotFound(err error) bool {
+	var sdkErr *tea.SDKError
+	if !errors.As(err, &sdkErr) {
+		return false
+	}
+	code := tea.StringValue(sdkErr.Code)
+	return tea.IntValue(sdkErr.StatusCode) == http.StatusNotFound ||
+		strings.Contains(code, "NotFound") || strings.Contains(code, "NotExist")
+}
+
 // extractMainDomain 从完整域名提取主域名
 func extractMainDomain(domain string) string {
 	parts := strings.Split(domain, ".")
@@ -0,0 +1,86 @@
+package aliyun
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"ssl-manager/internal/config"
+)
+
+// fakeCAS 模拟阿里云 CAS API，记录调用的 API 和参数
+type fakeCAS struct {
+	mu        sync.Mutex
+	actions   []string
+	cancelled []string // CancelCertificateForPackageRequest 的 OrderId
+}
+
+func (f *fakeCAS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	action := r.Header.Get("X-Acs-Action")
+	query := r.URL.Query()
+	f.mu.Lock()
+	f.actions = append(f.actions, action)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.Contains(r.Header.Get("Authorization"), "Credential=LTAI-test,") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"Code": "InvalidAccessKeyId.NotFound", "RequestId": "test"})
+		return
+	}
+
+	resp := map[string]interface{}{"RequestId": "test"}
+	switch action {
+	case "ListUserCertificateOrder":
+		// 申请的证书有订单，上传的证书订单ID为 0
+		resp["TotalCount"] = 2
+		resp["CertificateOrderList"] = []map[string]interface{}{
+			{"CertificateId": 1001, "OrderId": 9001, "CommonName": "www.example.com", "CertEndTime": 4102444800000},
+			{"CertificateId": 1002, "OrderId": 0, "CommonName": "upload.example.com", "CertEndTime": 4102444800000},
+		}
+	case "CancelCertificateForPackageRequest":
+		f.mu.Lock()
+		f.cancelled = append(f.cancelled, query.Get("OrderId"))
+		f.mu.Unlock()
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		resp["Code"] = "InvalidAction"
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestRevokeCertificateCancelsOrder(t *testing.T) {
+	fake := &fakeCAS{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	p, err := newCertProvider(&config.AliyunConfig{AccessKeyID: "LTAI-test", AccessKeySecret: "secret"}, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := p.RevokeCertificate(context.Background(), "1001", "keyCompromise")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("阿里云吊销不需要DNS验证记录: %v", records)
+	}
+	if len(fake.cancelled) != 1 || fake.cancelled[0] != "9001" {
+		t.Fatalf("吊销的订单 = %v, 期望 [9001]", fake.cancelled)
+	}
+
+	// 上传的证书和不存在的证书没有订单，不能吊销
+	for _, certID := range []string{"1002", "1003"} {
+		if _, err := p.RevokeCertificate(context.Background(), certID, ""); err == nil {
+			t.Errorf("证书 %s 应吊销失败", certID)
+		}
+	}
+	if len(fake.cancelled) != 1 {
+		t.Errorf("没有订单的证书不应调用吊销接口: %v", fake.cancelled)
+	}
+}
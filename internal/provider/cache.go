@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"context"
+	"sync"
+)
+
+// CertListCache 证书列表缓存
+// 同一次运行中，同一提供商下的所有域名共享一份证书列表，避免每个域名都全量查询一次
+type CertListCache struct {
+	mu     sync.Mutex
+	certs  []*CertificateInfo
+	loaded bool
+}
+
+// Get 返回缓存的证书列表，尚未加载时调用 load 加载
+// 并发调用时只会加载一次，其他调用方等待加载完成
+func (c *CertListCache) Get(ctx context.Context, load func(ctx context.Context) ([]*CertificateInfo, error)) ([]*CertificateInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.loaded {
+		return c.certs, nil
+	}
+
+	certs, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.certs = certs
+	c.loaded = true
+	return certs, nil
+}
+
+// Reset 清空缓存，下次 Get 时重新加载
+func (c *CertListCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.certs = nil
+	c.loaded = false
+}
@@ -10,18 +10,42 @@ type CertProvider interface {
 	// ApplyCertificate 申请证书，返回订单ID
 	ApplyCertificate(ctx context.Context, domain string) (orderID string, err error)
 
+	// ListPendingOrders 列出域名进行中（尚未签发）的订单，按创建时间从新到旧排序
+	ListPendingOrders(ctx context.Context, domain string) ([]*OrderInfo, error)
+
+	// ListOrders 列出所有证书订单，按创建时间从新到旧排序
+	ListOrders(ctx context.Context) ([]*OrderInfo, error)
+
+	// CancelOrder 取消证书订单
+	CancelOrder(ctx context.Context, orderID string) error
+
 	// GetCertificateStatus 获取证书状态
 	GetCertificateStatus(ctx context.Context, orderID string) (*CertificateStatus, error)
 
 	// DownloadCertificate 下载证书（通过订单ID）
 	DownloadCertificate(ctx context.Context, orderID string) (*Certificate, error)
 
-	// ListCertificates 列出已签发的证书
+	// ListCertificates 列出已签发的证书（自动翻页，结果在一次运行内缓存）
 	ListCertificates(ctx context.Context) ([]*CertificateInfo, error)
 
+	// ResetCache 清空证书列表缓存，每次运行开始时调用
+	ResetCache()
+
 	// FindValidCertificate 查找域名的有效证书（剩余有效期大于minDays天）
 	FindValidCertificate(ctx context.Context, domain string, minDays int) (*CertificateInfo, error)
 
 	// GetCertificateDetail 获取证书详情（通过证书ID）
 	GetCertificateDetail(ctx context.Context, certID string) (*Certificate, error)
+
+	// ListExpiredCertificates 列出已过期的证书
+	ListExpiredCertificates(ctx context.Context) ([]*CertificateInfo, error)
+
+	// ListBoundResources 列出证书仍绑定的云资源，未绑定时返回空列表
+	ListBoundResources(ctx context.Context, certID string) ([]string, error)
+
+	// DeleteCertificate 删除证书（通过证书ID）
+	DeleteCertificate(ctx context.Context, certID string) error
+
+	// RevokeCertificate 吊销证书，返回完成吊销所需添加的DNS验证记录（不需要时为空）
+	RevokeCertificate(ctx context.Context, certID, reason string) ([]*DNSRecord, error)
 }
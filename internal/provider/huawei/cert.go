@@ -2,12 +2,15 @@ package huawei
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/sdkerr"
 	scm "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/scm/v3"
 	scmModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/scm/v3/model"
 	scmRegion "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/scm/v3/region"
@@ -18,7 +21,8 @@ import (
 
 // CertProvider 华为云证书提供商
 type CertProvider struct {
-	client *scm.ScmClient
+	client    *scm.ScmClient
+	certCache provider.CertListCache
 }
 
 // NewCertProvider 创建华为云证书提供商
@@ -62,6 +66,22 @@ func (p *CertProvider) ApplyCertificate(ctx context.Context, domain string) (str
 	return "", fmt.Errorf("华为云暂不支持通过API申请免费证书，请在控制台手动申请后使用此工具管理")
 }
 
+// ListPendingOrders 列出域名进行中的订单
+// 华为云不支持通过API申请证书，因此不存在由本工具创建的进行中订单
+func (p *CertProvider) ListPendingOrders(ctx context.Context, domain string) ([]*provider.OrderInfo, error) {
+	return nil, nil
+}
+
+// ListOrders 列出所有证书订单
+func (p *CertProvider) ListOrders(ctx context.Context) ([]*provider.OrderInfo, error) {
+	return nil, nil
+}
+
+// CancelOrder 取消证书订单
+func (p *CertProvider) CancelOrder(ctx context.Context, certID string) error {
+	return fmt.Errorf("华为云暂不支持通过API取消证书订单，请在控制台操作")
+}
+
 // GetCertificateStatus 获取证书状态
 func (p *CertProvider) GetCertificateStatus(ctx context.Context, certID string) (*provider.CertificateStatus, error) {
 	request := &scmModel.ShowCertificateRequest{
@@ -70,6 +90,10 @@ func (p *CertProvider) GetCertificateStatus(ctx context.Context, certID string)
 
 	response, err := p.client.ShowCertificate(request)
 	if err != nil {
+		var respErr *sdkerr.ServiceResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("获取证书状态失败: %w (%w)", provider.ErrOrderNotFound, err)
+		}
 		return nil, fmt.Errorf("获取证书状态失败: %w", err)
 	}
 
@@ -122,23 +146,51 @@ func (p *CertProvider) DownloadCertificate(ctx context.Context, certID string) (
 		Certificate: certificate,
 		PrivateKey:  privateKey,
 		Chain:       chain,
+		Metadata:    provider.Metadata{CertID: certID, OrderID: certID}, // 华为云的订单ID即证书ID
 	}, nil
 }
 
 // ListCertificates 列出已签发的证书
 func (p *CertProvider) ListCertificates(ctx context.Context) ([]*provider.CertificateInfo, error) {
-	request := &scmModel.ListCertificatesRequest{}
+	return p.certCache.Get(ctx, func(ctx context.Context) ([]*provider.CertificateInfo, error) {
+		return p.listCertificates("ISSUED", "issued")
+	})
+}
 
-	response, err := p.client.ListCertificates(request)
-	if err != nil {
-		return nil, fmt.Errorf("获取证书列表失败: %w", err)
-	}
+// ResetCache 清空证书列表缓存
+func (p *CertProvider) ResetCache() {
+	p.certCache.Reset()
+}
+
+// ListExpiredCertificates 列出已过期的证书
+func (p *CertProvider) ListExpiredCertificates(ctx context.Context) ([]*provider.CertificateInfo, error) {
+	return p.listCertificates("EXPIRED", "expired")
+}
+
+// listCertificates 按华为云状态查询证书列表，并标记为统一状态
+func (p *CertProvider) listCertificates(huaweiStatus, status string) ([]*provider.CertificateInfo, error) {
+	const pageSize = 50 // 华为云单页最多50条
 
 	var certs []*provider.CertificateInfo
-	if response.Certificates != nil {
-		for _, cert := range *response.Certificates {
-			// 只返回已签发的证书
-			if cert.Status != "ISSUED" {
+	for offset := int32(0); ; offset += pageSize {
+		request := &scmModel.ListCertificatesRequest{
+			Status: &huaweiStatus,
+			Limit:  int32Ptr(pageSize),
+			Offset: int32Ptr(offset),
+		}
+
+		response, err := p.client.ListCertificates(request)
+		if err != nil {
+			return nil, fmt.Errorf("获取证书列表失败: %w", err)
+		}
+
+		var page []scmModel.CertificateDetail
+		if response.Certificates != nil {
+			page = *response.Certificates
+		}
+
+		for _, cert := range page {
+			if cert.Status != huaweiStatus {
 				continue
 			}
 
@@ -147,24 +199,61 @@ func (p *CertProvider) ListCertificates(ctx context.Context) ([]*provider.Certif
 				notAfter, _ = time.Parse("2006-01-02 15:04:05", cert.ExpireTime)
 			}
 
+			// 列表接口不返回生效时间，已签发的证书单独查询（清理被替代的证书时需要）
+			var notBefore time.Time
+			if huaweiStatus == "ISSUED" {
+				notBefore = p.notBefore(cert.Id)
+			}
+
 			var sans []string
 			if cert.Sans != "" {
 				sans = strings.Split(cert.Sans, ",")
 			}
 
 			certs = append(certs, &provider.CertificateInfo{
-				CertID:   cert.Id,
-				Domain:   cert.Domain,
-				Sans:     sans,
-				NotAfter: notAfter,
-				Status:   "issued",
+				CertID:    cert.Id,
+				Domain:    cert.Domain,
+				Sans:      sans,
+				NotBefore: notBefore,
+				NotAfter:  notAfter,
+				Status:    status,
 			})
 		}
+
+		// 已取完所有记录或当前页不满时结束
+		var total int32
+		if response.TotalCount != nil {
+			total = *response.TotalCount
+		}
+		if len(page) < pageSize || (total > 0 && offset+pageSize >= total) {
+			break
+		}
 	}
 
 	return certs, nil
 }
 
+// notBefore 查询证书生效时间，没有生效时间时使用签发时间，查询失败时返回零值
+func (p *CertProvider) notBefore(certID string) time.Time {
+	response, err := p.client.ShowCertificate(&scmModel.ShowCertificateRequest{CertificateId: certID})
+	if err != nil {
+		log.Printf("[华为云] 查询证书 %s 生效时间失败: %v", certID, err)
+		return time.Time{}
+	}
+	for _, value := range []*string{response.NotBefore, response.IssueTime} {
+		if value == nil || *value == "" {
+			continue
+		}
+		if t, err := time.Parse("2006-01-02 15:04:05", *value); err == nil {
+			return t
+		}
+		if t, err := time.Parse(time.RFC3339, *value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
 // FindValidCertificate 查找域名的有效证书
 func (p *CertProvider) FindValidCertificate(ctx context.Context, domain string, minDays int) (*provider.CertificateInfo, error) {
 	certs, err := p.ListCertificates(ctx)
@@ -199,6 +288,57 @@ func (p *CertProvider) GetCertificateDetail(ctx context.Context, certID string)
 	return p.DownloadCertificate(ctx, certID)
 }
 
+// ListBoundResources 列出证书绑定的云资源
+func (p *CertProvider) ListBoundResources(ctx context.Context, certID string) ([]string, error) {
+	request := &scmModel.ListDeployedResourcesRequest{
+		Body: &scmModel.ListDeployedResourcesRequestBody{
+			CertificateIds: []string{certID},
+			ServiceNames:   []string{"ALL"},
+		},
+	}
+
+	response, err := p.client.ListDeployedResources(request)
+	if err != nil {
+		return nil, fmt.Errorf("获取证书绑定资源失败: %w", err)
+	}
+
+	var resources []string
+	if response.Results != nil {
+		for _, result := range *response.Results {
+			if result.CertificateId != certID {
+				continue
+			}
+			for _, deployed := range result.DeployedResources {
+				if deployed.ResourceNum > 0 {
+					resources = append(resources, fmt.Sprintf("%s:%d", deployed.Service, deployed.ResourceNum))
+				}
+			}
+		}
+	}
+
+	return resources, nil
+}
+
+// DeleteCertificate 删除证书
+func (p *CertProvider) DeleteCertificate(ctx context.Context, certID string) error {
+	request := &scmModel.DeleteCertificateRequest{
+		CertificateId: certID,
+	}
+
+	if _, err := p.client.DeleteCertificate(request); err != nil {
+		return fmt.Errorf("删除证书失败: %w", err)
+	}
+	p.certCache.Reset()
+
+	log.Printf("[华为云] 证书 %s 已删除", certID)
+	return nil
+}
+
+// RevokeCertificate 吊销证书
+func (p *CertProvider) RevokeCertificate(ctx context.Context, certID, reason string) ([]*provider.DNSRecord, error) {
+	return nil, fmt.Errorf("华为云暂不支持通过API吊销证书，请在控制台吊销证书 %s", certID)
+}
+
 // extractMainDomain 从完整域名提取主域名
 func extractMainDomain(domain string) string {
 	parts := strings.Split(domain, ".")
@@ -208,6 +348,11 @@ func extractMainDomain(domain string) string {
 	return domain
 }
 
+// int32Ptr 返回 int32 指针
+func int32Ptr(v int32) *int32 {
+	return &v
+}
+
 // containsDomain 检查域名列表是否包含指定域名
 func containsDomain(domains []string, domain string) bool {
 	for _, d := range domains {
@@ -0,0 +1,68 @@
+package huawei
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
+	scm "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/scm/v3"
+)
+
+// fakeSCM 模拟华为云 SCM 证书列表和证书详情 API
+func fakeSCM(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/v3/scm/certificates":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"total_count": 2,
+			"certificates": []map[string]interface{}{
+				{"id": "scs-new", "domain": "www.example.com", "status": "ISSUED", "expire_time": "2027-01-01 00:00:00"},
+				{"id": "scs-old", "domain": "www.example.com", "status": "ISSUED", "expire_time": "2026-06-01 00:00:00"},
+			},
+		})
+	case r.URL.Path == "/v3/scm/certificates/scs-new":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": "scs-new", "status": "ISSUED",
+			"issue_time": "2026-09-30 08:00:00", "not_before": "2026-10-01 00:00:00",
+		})
+	case strings.HasPrefix(r.URL.Path, "/v3/scm/certificates/"):
+		// 没有生效时间时使用签发时间
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": "scs-old", "status": "ISSUED", "issue_time": "2025-06-01 00:00:00",
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error_code": "SCM.0404", "error_msg": "not found"})
+	}
+}
+
+func TestListCertificatesNotBefore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(fakeSCM))
+	defer server.Close()
+
+	auth := basic.NewCredentialsBuilder().WithAk("ak").WithSk("sk").WithProjectId("project").Build()
+	client := scm.NewScmClient(scm.ScmClientBuilder().WithEndpoints([]string{server.URL}).WithCredential(auth).Build())
+	p := &CertProvider{client: client}
+
+	certs, err := p.ListCertificates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Time{
+		"scs-new": time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		"scs-old": time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if len(certs) != len(want) {
+		t.Fatalf("证书数量 = %d, 期望 %d", len(certs), len(want))
+	}
+	for _, cert := range certs {
+		if !cert.NotBefore.Equal(want[cert.CertID]) {
+			t.Errorf("证书 %s 生效时间 = %v, 期望 %v", cert.CertID, cert.NotBefore, want[cert.CertID])
+		}
+	}
+}
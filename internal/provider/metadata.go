@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"ssl-manager/internal/certutil"
+)
+
+// FillMetadata 从证书内容中解析元数据，证书ID和订单ID保持提供商返回的值
+func (c *Certificate) FillMetadata() error {
+	leaf, err := certutil.ParseCertificatePEM(c.Certificate)
+	if err != nil {
+		return err
+	}
+
+	c.Serial = certutil.SerialHex(leaf)
+	c.Issuer = leaf.Issuer.CommonName
+	if c.Issuer == "" {
+		c.Issuer = leaf.Issuer.String()
+	}
+	c.SANs = certificateDomains(leaf)
+	c.NotBefore = leaf.NotBefore
+	c.NotAfter = leaf.NotAfter
+	c.KeyType = keyType(leaf)
+	c.Fingerprint = certutil.Fingerprint(leaf)
+	return nil
+}
+
+// certificateDomains 返回证书包含的域名（SAN，没有 SAN 时为 CN）
+func certificateDomains(leaf *x509.Certificate) []string {
+	if len(leaf.DNSNames) > 0 {
+		return append([]string{}, leaf.DNSNames...)
+	}
+	if leaf.Subject.CommonName != "" {
+		return []string{leaf.Subject.CommonName}
+	}
+	return nil
+}
+
+// keyType 返回证书公钥的类型和长度
+func keyType(leaf *x509.Certificate) string {
+	switch key := leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + strings.ReplaceAll(key.Curve.Params().Name, "-", "")
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return leaf.PublicKeyAlgorithm.String()
+	}
+}
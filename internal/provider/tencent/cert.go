@@ -2,12 +2,15 @@ package tencent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	ssl "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ssl/v20191205"
 
@@ -17,7 +20,8 @@ import (
 
 // CertProvider 腾讯云证书提供商
 type CertProvider struct {
-	client *ssl.Client
+	client    *ssl.Client
+	certCache provider.CertListCache
 }
 
 // NewCertProvider 创建腾讯云证书提供商
@@ -63,6 +67,91 @@ func (p *CertProvider) ApplyCertificate(ctx context.Context, domain string) (str
 	return certID, nil
 }
 
+// ListPendingOrders 列出域名进行中的订单
+func (p *CertProvider) ListPendingOrders(ctx context.Context, domain string) ([]*provider.OrderInfo, error) {
+	request := ssl.NewDescribeCertificatesRequest()
+	request.SearchKey = common.StringPtr(domain)
+	// 0: 审核中, 4: DNS记录添加中
+	request.CertificateStatus = common.Uint64Ptrs([]uint64{0, 4})
+
+	orders, err := p.listOrders(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// SearchKey 为模糊匹配，需要精确过滤
+	var pending []*provider.OrderInfo
+	for _, order := range orders {
+		if order.Domain == domain {
+			pending = append(pending, order)
+		}
+	}
+
+	return pending, nil
+}
+
+// ListOrders 列出所有证书订单
+// 腾讯云的证书即订单，CertificateId 同时作为订单ID使用
+func (p *CertProvider) ListOrders(ctx context.Context) ([]*provider.OrderInfo, error) {
+	return p.listOrders(ssl.NewDescribeCertificatesRequest())
+}
+
+// listOrders 查询证书并转换为统一的订单格式
+func (p *CertProvider) listOrders(request *ssl.DescribeCertificatesRequest) ([]*provider.OrderInfo, error) {
+	list, err := p.describeCertificates(request)
+	if err != nil {
+		return nil, fmt.Errorf("获取订单列表失败: %w", err)
+	}
+
+	var orders []*provider.OrderInfo
+	for _, cert := range list {
+		if cert.CertificateId == nil {
+			continue
+		}
+
+		domain := ""
+		if cert.Domain != nil {
+			domain = *cert.Domain
+		}
+
+		status := "pending"
+		if cert.Status != nil {
+			status = mapTencentStatus(*cert.Status)
+		}
+
+		var createdAt time.Time
+		if cert.InsertTime != nil {
+			createdAt, _ = time.ParseInLocation("2006-01-02 15:04:05", *cert.InsertTime, time.Local)
+		}
+
+		orders = append(orders, &provider.OrderInfo{
+			OrderID:   *cert.CertificateId,
+			Domain:    domain,
+			Status:    status,
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+
+	return orders, nil
+}
+
+// CancelOrder 取消证书订单
+func (p *CertProvider) CancelOrder(ctx context.Context, certID string) error {
+	request := ssl.NewCancelCertificateOrderRequest()
+	request.CertificateId = common.StringPtr(certID)
+
+	if _, err := p.client.CancelCertificateOrder(request); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+
+	log.Printf("[腾讯云] 订单 %s 已取消", certID)
+	return nil
+}
+
 // GetCertificateStatus 获取证书状态
 func (p *CertProvider) GetCertificateStatus(ctx context.Context, certID string) (*provider.CertificateStatus, error) {
 	request := ssl.NewDescribeCertificateRequest()
@@ -70,6 +159,9 @@ func (p *CertProvider) GetCertificateStatus(ctx context.Context, certID string)
 
 	response, err := p.client.DescribeCertificate(request)
 	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("获取证书状态失败: %w (%w)", provider.ErrOrderNotFound, err)
+		}
 		return nil, fmt.Errorf("获取证书状态失败: %w", err)
 	}
 
@@ -172,27 +264,49 @@ func (p *CertProvider) DownloadCertificate(ctx context.Context, certID string) (
 		Certificate: certificate,
 		PrivateKey:  privateKey,
 		Chain:       certificate,
+		Metadata:    provider.Metadata{CertID: certID, OrderID: certID}, // 腾讯云的订单ID即证书ID
 	}, nil
 }
 
 // ListCertificates 列出已签发的证书
 func (p *CertProvider) ListCertificates(ctx context.Context) ([]*provider.CertificateInfo, error) {
+	return p.certCache.Get(ctx, func(ctx context.Context) ([]*provider.CertificateInfo, error) {
+		// 1: 已通过
+		return p.listCertificates(1, "issued")
+	})
+}
+
+// ResetCache 清空证书列表缓存
+func (p *CertProvider) ResetCache() {
+	p.certCache.Reset()
+}
+
+// ListExpiredCertificates 列出已过期的证书
+func (p *CertProvider) ListExpiredCertificates(ctx context.Context) ([]*provider.CertificateInfo, error) {
+	// 3: 已过期
+	return p.listCertificates(3, "expired")
+}
+
+// listCertificates 按腾讯云状态码查询证书列表，并标记为统一状态
+func (p *CertProvider) listCertificates(tencentStatus uint64, status string) ([]*provider.CertificateInfo, error) {
 	request := ssl.NewDescribeCertificatesRequest()
-	request.Limit = common.Uint64Ptr(100)
+	request.CertificateStatus = common.Uint64Ptrs([]uint64{tencentStatus})
 
-	response, err := p.client.DescribeCertificates(request)
+	list, err := p.describeCertificates(request)
 	if err != nil {
 		return nil, fmt.Errorf("获取证书列表失败: %w", err)
 	}
 
 	var certs []*provider.CertificateInfo
-	for _, cert := range response.Response.Certificates {
-		// 只返回已签发的证书
-		if cert.Status == nil || *cert.Status != 1 {
+	for _, cert := range list {
+		if cert.Status == nil || *cert.Status != tencentStatus {
 			continue
 		}
 
-		var notAfter time.Time
+		var notBefore, notAfter time.Time
+		if cert.CertBeginTime != nil {
+			notBefore, _ = time.Parse("2006-01-02 15:04:05", *cert.CertBeginTime)
+		}
 		if cert.CertEndTime != nil {
 			notAfter, _ = time.Parse("2006-01-02 15:04:05", *cert.CertEndTime)
 		}
@@ -212,17 +326,48 @@ func (p *CertProvider) ListCertificates(ctx context.Context) ([]*provider.Certif
 		}
 
 		certs = append(certs, &provider.CertificateInfo{
-			CertID:   *cert.CertificateId,
-			Domain:   domain,
-			Sans:     sans,
-			NotAfter: notAfter,
-			Status:   "issued",
+			CertID:    *cert.CertificateId,
+			Domain:    domain,
+			Sans:      sans,
+			NotBefore: notBefore,
+			NotAfter:  notAfter,
+			Status:    status,
 		})
 	}
 
 	return certs, nil
 }
 
+// describeCertificates 分页查询证书列表，返回所有页的结果
+func (p *CertProvider) describeCertificates(request *ssl.DescribeCertificatesRequest) ([]*ssl.Certificates, error) {
+	const pageSize = 100
+
+	var all []*ssl.Certificates
+	request.Limit = common.Uint64Ptr(pageSize)
+	for offset := uint64(0); ; offset += pageSize {
+		request.Offset = common.Uint64Ptr(offset)
+
+		response, err := p.client.DescribeCertificates(request)
+		if err != nil {
+			return nil, err
+		}
+
+		list := response.Response.Certificates
+		all = append(all, list...)
+
+		// 已取完所有记录或当前页不满时结束
+		var total uint64
+		if response.Response.TotalCount != nil {
+			total = *response.Response.TotalCount
+		}
+		if len(list) < pageSize || (total > 0 && uint64(len(all)) >= total) {
+			break
+		}
+	}
+
+	return all, nil
+}
+
 // FindValidCertificate 查找域名的有效证书
 func (p *CertProvider) FindValidCertificate(ctx context.Context, domain string, minDays int) (*provider.CertificateInfo, error) {
 	certs, err := p.ListCertificates(ctx)
@@ -279,9 +424,102 @@ func (p *CertProvider) GetCertificateDetail(ctx context.Context, certID string)
 		Certificate: certificate,
 		PrivateKey:  privateKey,
 		Chain:       certificate,
+		Metadata:    provider.Metadata{CertID: certID, OrderID: certID}, // 腾讯云的订单ID即证书ID
 	}, nil
 }
 
+// ListBoundResources 列出证书绑定的云资源
+func (p *CertProvider) ListBoundResources(ctx context.Context, certID string) ([]string, error) {
+	request := ssl.NewDescribeCertificatesRequest()
+	request.SearchKey = common.StringPtr(certID)
+
+	response, err := p.client.DescribeCertificates(request)
+	if err != nil {
+		return nil, fmt.Errorf("获取证书绑定资源失败: %w", err)
+	}
+
+	var resources []string
+	for _, cert := range response.Response.Certificates {
+		if cert.CertificateId == nil || *cert.CertificateId != certID {
+			continue
+		}
+		for _, resource := range cert.BoundResource {
+			if resource != nil {
+				resources = append(resources, *resource)
+			}
+		}
+	}
+
+	return resources, nil
+}
+
+// DeleteCertificate 删除证书
+func (p *CertProvider) DeleteCertificate(ctx context.Context, certID string) error {
+	request := ssl.NewDeleteCertificateRequest()
+	request.CertificateId = common.StringPtr(certID)
+	// 由腾讯云再次检查证书是否仍绑定云资源，绑定时拒绝删除
+	request.IsCheckResource = common.BoolPtr(true)
+
+	response, err := p.client.DeleteCertificate(request)
+	if err != nil {
+		return fmt.Errorf("删除证书失败: %w", err)
+	}
+	if response.Response.DeleteResult != nil && !*response.Response.DeleteResult {
+		return fmt.Errorf("删除证书失败: 证书可能仍绑定云资源")
+	}
+	p.certCache.Reset()
+
+	log.Printf("[腾讯云] 证书 %s 已删除", certID)
+	return nil
+}
+
+// RevokeCertificate 吊销证书
+// 腾讯云DV证书吊销需要完成域名验证，返回需要添加的DNS记录
+func (p *CertProvider) RevokeCertificate(ctx context.Context, certID, reason string) ([]*provider.DNSRecord, error) {
+	request := ssl.NewRevokeCertificateRequest()
+	request.CertificateId = common.StringPtr(certID)
+	if reason != "" {
+		request.Reason = common.StringPtr(reason)
+	}
+
+	response, err := p.client.RevokeCertificate(request)
+	if err != nil {
+		return nil, fmt.Errorf("吊销证书失败: %w", err)
+	}
+
+	var records []*provider.DNSRecord
+	for _, auth := range response.Response.RevokeDomainValidateAuths {
+		if auth.DomainValidateAuthKey == nil || auth.DomainValidateAuthValue == nil || auth.DomainValidateAuthDomain == nil {
+			continue
+		}
+		domain := *auth.DomainValidateAuthDomain
+		rr := *auth.DomainValidateAuthKey
+		// 验证KEY可能只是主机记录前缀，补全为完整记录名
+		if !strings.HasSuffix(rr, extractMainDomain(domain)) {
+			rr = rr + "." + domain
+		}
+		records = append(records, &provider.DNSRecord{
+			Domain: domain,
+			RR:     rr,
+			Type:   "TXT",
+			Value:  *auth.DomainValidateAuthValue,
+		})
+	}
+
+	p.certCache.Reset()
+	log.Printf("[腾讯云] 证书 %s 吊销申请已提交", certID)
+	return records, nil
+}
+
+// isNotFound 检查腾讯云返回的错误是否表示资源不存在（ResourceNotFound、FailedOperation.CertificateNotFound 等）
+func isNotFound(err error) bool {
+	var sdkErr *tcerr.TencentCloudSDKError
+	if !errors.As(err, &sdkErr) {
+		return false
+	}
+	return strings.Contains(sdkErr.Code, "NotFound") || strings.Contains(sdkErr.Code, "NotExist")
+}
+
 // extractMainDomain 从完整域名提取主域名
 func extractMainDomain(domain string) string {
 	parts := strings.Split(domain, ".")
@@ -1,6 +1,12 @@
 package provider
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrOrderNotFound 云平台上不存在该订单（已删除或订单ID无效），GetCertificateStatus 返回包含该错误的错误时订单不再恢复
+var ErrOrderNotFound = errors.New("订单不存在")
 
 // CertificateStatus 证书状态
 type CertificateStatus struct {
@@ -12,11 +18,27 @@ type CertificateStatus struct {
 	RecordValue  string // DNS验证记录值
 }
 
-// Certificate 证书内容
+// Certificate 证书内容和元数据
 type Certificate struct {
 	Certificate string // 证书内容 (PEM格式)
 	PrivateKey  string // 私钥 (PEM格式)
 	Chain       string // 证书链 (可选)
+
+	// 元数据，提供商只需填写证书ID和订单ID，其余字段由 FillMetadata 从证书中解析
+	Metadata
+}
+
+// Metadata 证书元数据，保存为证书目录下的 meta.json
+type Metadata struct {
+	CertID      string    `json:"cert_id,omitempty"`  // 云平台证书ID
+	OrderID     string    `json:"order_id,omitempty"` // 订单ID
+	Serial      string    `json:"serial"`             // 序列号（小写十六进制）
+	Issuer      string    `json:"issuer"`             // 签发者
+	SANs        []string  `json:"sans"`               // 证书包含的域名
+	NotBefore   time.Time `json:"not_before"`         // 生效时间
+	NotAfter    time.Time `json:"not_after"`          // 过期时间
+	KeyType     string    `json:"key_type"`           // 密钥类型，如 RSA-2048、ECDSA-P256
+	Fingerprint string    `json:"fingerprint"`        // SHA-256 指纹（小写十六进制）
 }
 
 // CertificateInfo 证书信息
@@ -30,6 +52,14 @@ type CertificateInfo struct {
 	Status    string    // 状态
 }
 
+// OrderInfo 证书订单信息
+type OrderInfo struct {
+	OrderID   string    // 订单ID
+	Domain    string    // 域名
+	Status    string    // 状态: pending, domain_verify, process, certificate, failed
+	CreatedAt time.Time // 创建时间
+}
+
 // DNSRecord DNS记录
 type DNSRecord struct {
 	RecordID string // 记录ID
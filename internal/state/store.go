@@ -0,0 +1,654 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 订单在本地状态库中的终态
+const (
+	OrderCompleted = "completed" // 证书已下载保存
+	OrderFailed    = "failed"    // 申请失败
+	OrderCancelled = "cancelled" // 已取消
+)
+
+// 状态库中最多保留的记录数
+const (
+	maxRuns                  = 200 // 运行记录
+	maxOrders                = 200 // 已结束的订单（进行中的订单始终保留）
+	maxCertificatesPerDomain = 20  // 每个域名的证书记录
+)
+
+// Store 本地状态库
+// 记录订单、证书、吊销操作和每次运行的结果，以JSON文件保存，每次修改整体原子替换
+// 守护进程和命令行可能同时修改状态库：修改时持有 <状态文件>.lock 的排他文件锁，并在锁内重新读取状态文件后再应用修改
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	data    stateData
+	modTime time.Time // 最近一次读取或写入时状态文件的修改时间，用于判断是否需要重新读取
+	size    int64
+}
+
+// stateData 状态文件内容
+type stateData struct {
+	Orders       []*OrderRecord       `json:"orders"`
+	Certificates []*CertificateRecord `json:"certificates"`
+	Revocations  []*RevocationRecord  `json:"revocations"`
+	Deployments  []*DeploymentRecord  `json:"deployments,omitempty"`
+	Drifts       []*DriftRecord       `json:"drifts,omitempty"`
+	Runs         []*RunRecord         `json:"runs"`
+}
+
+// OrderRecord 订单记录
+type OrderRecord struct {
+	OrderID     string              `json:"order_id"`              // 订单ID
+	Provider    string              `json:"provider"`              // 证书提供商
+	DNSProvider string              `json:"dns_provider"`          // DNS提供商
+	Domain      string              `json:"domain"`                // 域名
+	Status      string              `json:"status"`                // 当前状态
+	Transitions []*StatusTransition `json:"transitions,omitempty"` // 状态变化历史
+	DNSRecords  []*DNSRecordEntry   `json:"dns_records,omitempty"` // 已创建的DNS验证记录
+	CertID      string              `json:"cert_id,omitempty"`     // 签发的证书ID
+	Fingerprint string              `json:"fingerprint,omitempty"` // 签发证书的SHA-256指纹
+	Error       string              `json:"error,omitempty"`       // 失败原因
+	CreatedAt   time.Time           `json:"created_at"`            // 创建时间
+	UpdatedAt   time.Time           `json:"updated_at"`            // 更新时间
+}
+
+// StatusTransition 订单状态变化
+type StatusTransition struct {
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+}
+
+// DNSRecordEntry 订单创建的DNS记录
+type DNSRecordEntry struct {
+	RR        string    `json:"rr"`
+	Type      string    `json:"type"`
+	Value     string    `json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CertificateRecord 证书记录
+type CertificateRecord struct {
+	Domain      string    `json:"domain"`             // 域名
+	Provider    string    `json:"provider"`           // 证书提供商
+	CertID      string    `json:"cert_id,omitempty"`  // 证书ID
+	OrderID     string    `json:"order_id,omitempty"` // 订单ID
+	Serial      string    `json:"serial"`             // 序列号
+	Fingerprint string    `json:"fingerprint"`        // SHA-256指纹
+	NotBefore   time.Time `json:"not_before"`         // 生效时间
+	NotAfter    time.Time `json:"not_after"`          // 过期时间
+	SavedAt     time.Time `json:"saved_at"`           // 保存时间
+}
+
+// RevocationRecord 证书吊销记录
+type RevocationRecord struct {
+	Domain    string    `json:"domain"`           // 域名
+	Provider  string    `json:"provider"`         // 证书提供商
+	CertID    string    `json:"cert_id"`          // 被吊销的证书ID
+	Serial    string    `json:"serial,omitempty"` // 被吊销的证书序列号（OCSP 检查发现的吊销）
+	Reason    string    `json:"reason"`           // 吊销原因
+	RevokedAt time.Time `json:"revoked_at"`       // 吊销时间
+}
+
+// 部署结果
+const (
+	DeploySucceeded = "success"
+	DeployFailed    = "failed"
+)
+
+// DeploymentRecord 部署记录（每个域名的每个部署目标只保留最近一次）
+type DeploymentRecord struct {
+	Domain     string    `json:"domain"`               // 域名
+	Target     string    `json:"target"`               // 部署目标名称
+	Type       string    `json:"type"`                 // 部署目标类型
+	Serial     string    `json:"serial"`               // 部署的证书序列号
+	Status     string    `json:"status"`               // 结果: success, failed
+	Attempts   int       `json:"attempts"`             // 尝试次数
+	Error      string    `json:"error,omitempty"`      // 失败原因
+	DeployedAt time.Time `json:"deployed_at"`          // 部署时间
+	NotifiedAt time.Time `json:"notified_at,omitzero"` // 发送 deploy_failed 通知的时间，未通知时为空
+}
+
+// DriftRecord 线上端点的部署不一致记录（每个域名的每个端点只保留一条，恢复一致后删除）
+type DriftRecord struct {
+	Domain         string    `json:"domain"`          // 域名
+	Endpoint       string    `json:"endpoint"`        // 线上端点
+	ExpectedSerial string    `json:"expected_serial"` // 本地证书序列号
+	ServedSerial   string    `json:"served_serial"`   // 线上证书序列号
+	NotifiedAt     time.Time `json:"notified_at"`     // 发送 deploy_drift 通知的时间
+}
+
+// RunRecord 一次运行的记录
+type RunRecord struct {
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Results    []*DomainResult `json:"results"`
+
+	mu sync.Mutex
+}
+
+// DomainResult 单个域名的处理结果
+type DomainResult struct {
+	Domain  string `json:"domain"`
+	Outcome string `json:"outcome"` // success, failed
+	Error   string `json:"error,omitempty"`
+}
+
+// AddResult 记录域名处理结果（并发安全）
+func (r *RunRecord) AddResult(domain string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := &DomainResult{Domain: domain, Outcome: "success"}
+	if err != nil {
+		result.Outcome = "failed"
+		result.Error = err.Error()
+	}
+	r.Results = append(r.Results, result)
+}
+
+// errCorrupt 状态文件内容无法解析
+var errCorrupt = errors.New("解析状态文件失败")
+
+// Open 打开状态库，文件不存在时创建空状态库，文件损坏时备份后使用空状态库
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.load(); err != nil {
+		if !errors.Is(err, errCorrupt) {
+			return nil, err
+		}
+		unlock, err := s.lockFile()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		if err := s.loadOrReset(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// load 读取状态文件，文件不存在时为空状态库（调用方需持有锁）
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.data = stateData{}
+			s.modTime, s.size = time.Time{}, 0
+			return nil
+		}
+		return fmt.Errorf("读取状态文件失败: %w", err)
+	}
+
+	var loaded stateData
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("%w: %w", errCorrupt, err)
+	}
+	s.data = loaded
+	s.recordFileInfo()
+	return nil
+}
+
+// loadOrReset 读取状态文件，无法解析时重命名为 <path>.corrupt-<时间> 并使用空状态库，
+// 避免一个损坏的状态文件导致程序无法启动（调用方需持有锁和文件锁）
+func (s *Store) loadOrReset() error {
+	err := s.load()
+	if !errors.Is(err, errCorrupt) {
+		return err
+	}
+
+	backup := fmt.Sprintf("%s.corrupt-%s", s.path, time.Now().Format("20060102-150405"))
+	if renameErr := os.Rename(s.path, backup); renameErr != nil {
+		return fmt.Errorf("备份损坏的状态文件失败: %w", renameErr)
+	}
+	log.Printf("状态文件 %s 已损坏 (%v)，已备份为 %s，使用空状态库", s.path, err, backup)
+	s.data = stateData{}
+	s.modTime, s.size = time.Time{}, 0
+	return nil
+}
+
+// refresh 状态文件被其他进程修改后重新读取，读取失败时保留内存中的状态（调用方需持有锁）
+func (s *Store) refresh() {
+	info, err := os.Stat(s.path)
+	if err != nil || (info.ModTime().Equal(s.modTime) && info.Size() == s.size) {
+		return
+	}
+	if err := s.load(); err != nil {
+		log.Printf("重新读取状态文件失败: %v", err)
+	}
+}
+
+// recordFileInfo 记录状态文件当前的修改时间和大小（调用方需持有锁）
+func (s *Store) recordFileInfo() {
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+}
+
+// update 在文件锁内重新读取状态文件，应用修改并保存，modify 返回 false 表示没有修改、不需要写入
+func (s *Store) update(modify func(data *stateData) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.loadOrReset(); err != nil {
+		return err
+	}
+	if !modify(&s.data) {
+		return nil
+	}
+	s.data.trim()
+	return s.save()
+}
+
+// lockFile 获取状态文件的排他锁，返回释放锁的函数
+func (s *Store) lockFile() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+	f, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开状态锁文件失败: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("锁定状态文件失败: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// trim 删除超出数量限制的旧记录
+func (d *stateData) trim() {
+	if len(d.Runs) > maxRuns {
+		d.Runs = d.Runs[len(d.Runs)-maxRuns:]
+	}
+
+	// 从新到旧保留已结束的订单，进行中的订单始终保留
+	finished := 0
+	for i := len(d.Orders) - 1; i >= 0; i-- {
+		switch d.Orders[i].Status {
+		case OrderCompleted, OrderFailed, OrderCancelled:
+			finished++
+			if finished > maxOrders {
+				d.Orders = append(d.Orders[:i], d.Orders[i+1:]...)
+			}
+		}
+	}
+
+	perDomain := map[string]int{}
+	for i := len(d.Certificates) - 1; i >= 0; i-- {
+		domain := d.Certificates[i].Domain
+		perDomain[domain]++
+		if perDomain[domain] > maxCertificatesPerDomain {
+			d.Certificates = append(d.Certificates[:i], d.Certificates[i+1:]...)
+		}
+	}
+}
+
+// save 将状态写入临时文件后原子替换（调用方需持有锁）
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(&s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化状态失败: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".state-*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入状态失败: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入状态失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入状态失败: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("替换状态文件失败: %w", err)
+	}
+	s.recordFileInfo()
+	return nil
+}
+
+// findOrder 查找订单记录
+func (d *stateData) findOrder(provider, orderID string) *OrderRecord {
+	for _, order := range d.Orders {
+		if order.Provider == provider && order.OrderID == orderID {
+			return order
+		}
+	}
+	return nil
+}
+
+// RecordOrder 记录订单，已存在时只更新DNS提供商和域名
+func (s *Store) RecordOrder(provider, dnsProvider, domain, orderID, status string) error {
+	return s.update(func(d *stateData) bool {
+		now := time.Now()
+		if order := d.findOrder(provider, orderID); order != nil {
+			order.DNSProvider = dnsProvider
+			order.Domain = domain
+			order.UpdatedAt = now
+			return true
+		}
+
+		d.Orders = append(d.Orders, &OrderRecord{
+			OrderID:     orderID,
+			Provider:    provider,
+			DNSProvider: dnsProvider,
+			Domain:      domain,
+			Status:      status,
+			Transitions: []*StatusTransition{{Status: status, At: now}},
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+		return true
+	})
+}
+
+// UpdateOrderStatus 更新订单状态，状态未变化时不写入
+func (s *Store) UpdateOrderStatus(provider, orderID, status string) error {
+	return s.update(func(d *stateData) bool {
+		order := d.findOrder(provider, orderID)
+		if order == nil || order.Status == status {
+			return false
+		}
+
+		now := time.Now()
+		order.Status = status
+		order.Transitions = append(order.Transitions, &StatusTransition{Status: status, At: now})
+		order.UpdatedAt = now
+		return true
+	})
+}
+
+// AddOrderDNSRecord 记录订单创建的DNS验证记录
+func (s *Store) AddOrderDNSRecord(provider, orderID, rr, recordType, value string) error {
+	return s.update(func(d *stateData) bool {
+		order := d.findOrder(provider, orderID)
+		if order == nil {
+			return false
+		}
+
+		now := time.Now()
+		order.DNSRecords = append(order.DNSRecords, &DNSRecordEntry{
+			RR:        rr,
+			Type:      recordType,
+			Value:     value,
+			CreatedAt: now,
+		})
+		order.UpdatedAt = now
+		return true
+	})
+}
+
+// CompleteOrder 标记订单已完成，并记录签发的证书
+func (s *Store) CompleteOrder(provider, orderID, certID, fingerprint string) error {
+	return s.update(func(d *stateData) bool {
+		order := d.findOrder(provider, orderID)
+		if order == nil {
+			return false
+		}
+
+		now := time.Now()
+		order.Status = OrderCompleted
+		order.Transitions = append(order.Transitions, &StatusTransition{Status: OrderCompleted, At: now})
+		order.CertID = certID
+		order.Fingerprint = fingerprint
+		order.Error = ""
+		order.UpdatedAt = now
+		return true
+	})
+}
+
+// FailOrder 标记订单失败或取消
+func (s *Store) FailOrder(provider, orderID, status, reason string) error {
+	return s.update(func(d *stateData) bool {
+		order := d.findOrder(provider, orderID)
+		if order == nil {
+			return false
+		}
+
+		now := time.Now()
+		order.Status = status
+		order.Transitions = append(order.Transitions, &StatusTransition{Status: status, At: now})
+		order.Error = reason
+		order.UpdatedAt = now
+		return true
+	})
+}
+
+// RecordCertificate 记录保存的证书，每个域名只保留最近的 maxCertificatesPerDomain 条
+func (s *Store) RecordCertificate(record *CertificateRecord) error {
+	if record.SavedAt.IsZero() {
+		record.SavedAt = time.Now()
+	}
+	return s.update(func(d *stateData) bool {
+		d.Certificates = append(d.Certificates, record)
+		return true
+	})
+}
+
+// RecordRevocation 记录吊销操作
+func (s *Store) RecordRevocation(record *RevocationRecord) error {
+	if record.RevokedAt.IsZero() {
+		record.RevokedAt = time.Now()
+	}
+	return s.update(func(d *stateData) bool {
+		d.Revocations = append(d.Revocations, record)
+		return true
+	})
+}
+
+// RecordDeployment 记录部署结果，替换同一域名和目标之前的记录
+func (s *Store) RecordDeployment(record *DeploymentRecord) error {
+	if record.DeployedAt.IsZero() {
+		record.DeployedAt = time.Now()
+	}
+	return s.update(func(d *stateData) bool {
+		for i, existing := range d.Deployments {
+			if existing.Domain == record.Domain && existing.Target == record.Target {
+				d.Deployments[i] = record
+				return true
+			}
+		}
+		d.Deployments = append(d.Deployments, record)
+		return true
+	})
+}
+
+// DriftNotified 判断端点的这次不一致是否已经通知过（本地、线上证书序列号与上次通知时相同）
+func (s *Store) DriftNotified(domain, endpoint, expectedSerial, servedSerial string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	for _, existing := range s.data.Drifts {
+		if existing.Domain == domain && existing.Endpoint == endpoint {
+			return existing.ExpectedSerial == expectedSerial && existing.ServedSerial == servedSerial
+		}
+	}
+	return false
+}
+
+// RecordDrift 记录已通知的部署不一致，同一域名端点只保留最新一条
+func (s *Store) RecordDrift(record *DriftRecord) error {
+	return s.update(func(d *stateData) bool {
+		for i, existing := range d.Drifts {
+			if existing.Domain == record.Domain && existing.Endpoint == record.Endpoint {
+				d.Drifts[i] = record
+				return true
+			}
+		}
+		d.Drifts = append(d.Drifts, record)
+		return true
+	})
+}
+
+// ClearDrift 端点恢复一致后删除不一致记录，之后再次不一致时会重新通知
+func (s *Store) ClearDrift(domain, endpoint string) error {
+	return s.update(func(d *stateData) bool {
+		for i, existing := range d.Drifts {
+			if existing.Domain == domain && existing.Endpoint == endpoint {
+				d.Drifts = append(d.Drifts[:i], d.Drifts[i+1:]...)
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// StartRun 开始记录一次运行
+func (s *Store) StartRun() *RunRecord {
+	return &RunRecord{StartedAt: time.Now()}
+}
+
+// FinishRun 保存运行记录，只保留最近的 maxRuns 次
+func (s *Store) FinishRun(run *RunRecord) error {
+	run.FinishedAt = time.Now()
+	return s.update(func(d *stateData) bool {
+		d.Runs = append(d.Runs, run)
+		return true
+	})
+}
+
+// Orders 返回所有订单记录的副本
+func (s *Store) Orders() []OrderRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	orders := make([]OrderRecord, 0, len(s.data.Orders))
+	for _, order := range s.data.Orders {
+		orders = append(orders, *order)
+	}
+	return orders
+}
+
+// InFlightOrders 返回尚未结束（未完成、未失败、未取消）的订单
+func (s *Store) InFlightOrders() []OrderRecord {
+	var inFlight []OrderRecord
+	for _, order := range s.Orders() {
+		switch order.Status {
+		case OrderCompleted, OrderFailed, OrderCancelled:
+			continue
+		}
+		inFlight = append(inFlight, order)
+	}
+	return inFlight
+}
+
+// LatestCertificate 返回域名最近保存的证书记录，没有时返回 nil
+func (s *Store) LatestCertificate(domain string) *CertificateRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	for i := len(s.data.Certificates) - 1; i >= 0; i-- {
+		if s.data.Certificates[i].Domain == domain {
+			record := *s.data.Certificates[i]
+			return &record
+		}
+	}
+	return nil
+}
+
+// Certificates 返回域名保存过的所有证书记录（按保存时间顺序）
+func (s *Store) Certificates(domain string) []CertificateRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	var records []CertificateRecord
+	for _, record := range s.data.Certificates {
+		if record.Domain == domain {
+			records = append(records, *record)
+		}
+	}
+	return records
+}
+
+// IsRevoked 检查域名的证书序列号是否有吊销记录
+func (s *Store) IsRevoked(domain, serial string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	for _, record := range s.data.Revocations {
+		if record.Domain == domain && record.Serial != "" && record.Serial == serial {
+			return true
+		}
+	}
+	return false
+}
+
+// Deployment 返回域名部署目标最近一次的部署记录，没有时返回 nil
+func (s *Store) Deployment(domain, target string) *DeploymentRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	for _, record := range s.data.Deployments {
+		if record.Domain == domain && record.Target == target {
+			copied := *record
+			return &copied
+		}
+	}
+	return nil
+}
+
+// Revocations 返回所有吊销记录
+func (s *Store) Revocations() []RevocationRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	records := make([]RevocationRecord, 0, len(s.data.Revocations))
+	for _, record := range s.data.Revocations {
+		records = append(records, *record)
+	}
+	return records
+}
+
+// Runs 返回最近 limit 次运行记录（从旧到新），limit <= 0 时返回全部
+func (s *Store) Runs(limit int) []*RunRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	runs := s.data.Runs
+	if limit > 0 && len(runs) > limit {
+		runs = runs[len(runs)-limit:]
+	}
+	return append([]*RunRecord(nil), runs...)
+}
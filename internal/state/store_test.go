@@ -0,0 +1,204 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStoreMergesWritesFromOtherProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	daemon, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := daemon.RecordOrder("aliyun", "aliyun", "www.example.com", "order-1", "domain_verify"); err != nil {
+		t.Fatal(err)
+	}
+	// 命令行在守护进程运行期间吊销证书，随后守护进程继续写入
+	if err := cli.RecordRevocation(&RevocationRecord{Domain: "www.example.com", Serial: "0a", Reason: "unspecified"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := daemon.RecordDeployment(&DeploymentRecord{Domain: "www.example.com", Target: "nfs", Serial: "0b", Status: DeploySucceeded}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.IsRevoked("www.example.com", "0a") {
+		t.Error("命令行写入的吊销记录被守护进程覆盖")
+	}
+	if reopened.Deployment("www.example.com", "nfs") == nil {
+		t.Error("缺少守护进程写入的部署记录")
+	}
+	if len(reopened.Orders()) != 1 {
+		t.Errorf("订单数 = %d, 期望 1", len(reopened.Orders()))
+	}
+	// 读取时发现文件已被修改，重新读取
+	if !daemon.IsRevoked("www.example.com", "0a") {
+		t.Error("守护进程没有读到命令行写入的吊销记录")
+	}
+}
+
+func TestStoreConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	const writers, records = 4, 10
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		store, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(w int, store *Store) {
+			defer wg.Done()
+			for i := 0; i < records; i++ {
+				target := fmt.Sprintf("target-%d-%d", w, i)
+				if err := store.RecordDeployment(&DeploymentRecord{Domain: "example.com", Target: target, Status: DeploySucceeded}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(w, store)
+	}
+	wg.Wait()
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for w := 0; w < writers; w++ {
+		for i := 0; i < records; i++ {
+			if store.Deployment("example.com", fmt.Sprintf("target-%d-%d", w, i)) == nil {
+				t.Errorf("缺少部署记录 target-%d-%d", w, i)
+			}
+		}
+	}
+}
+
+func TestStoreTrimsOrdersAndCertificates(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RecordOrder("aliyun", "aliyun", "example.com", "in-flight", "domain_verify"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxOrders+10; i++ {
+		orderID := fmt.Sprintf("order-%d", i)
+		if err := store.RecordOrder("aliyun", "aliyun", "example.com", orderID, "domain_verify"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.CompleteOrder("aliyun", orderID, "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orders := store.Orders()
+	if len(orders) != maxOrders+1 {
+		t.Fatalf("订单数 = %d, 期望 %d", len(orders), maxOrders+1)
+	}
+	if orders[0].OrderID != "in-flight" || orders[1].OrderID != "order-10" {
+		t.Errorf("保留的订单 = %s, %s", orders[0].OrderID, orders[1].OrderID)
+	}
+
+	for i := 0; i < maxCertificatesPerDomain+5; i++ {
+		if err := store.RecordCertificate(&CertificateRecord{Domain: "example.com", Serial: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.RecordCertificate(&CertificateRecord{Domain: "other.com", Serial: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	certs := store.Certificates("example.com")
+	if len(certs) != maxCertificatesPerDomain || certs[0].Serial != "5" {
+		t.Errorf("example.com 证书记录数 = %d, 第一条 = %s", len(certs), certs[0].Serial)
+	}
+	if len(store.Certificates("other.com")) != 1 {
+		t.Error("other.com 的证书记录被删除")
+	}
+}
+
+func TestStoreDriftRecords(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if store.DriftNotified("www.example.com", "1.2.3.4:443", "0b", "0a") {
+		t.Fatal("未记录的不一致不应视为已通知")
+	}
+	if err := store.RecordDrift(&DriftRecord{Domain: "www.example.com", Endpoint: "1.2.3.4:443", ExpectedSerial: "0b", ServedSerial: "0a"}); err != nil {
+		t.Fatal(err)
+	}
+	if !store.DriftNotified("www.example.com", "1.2.3.4:443", "0b", "0a") {
+		t.Error("相同序列号的不一致应视为已通知")
+	}
+	if store.DriftNotified("www.example.com", "1.2.3.4:443", "0c", "0a") {
+		t.Error("本地序列号变化后应重新通知")
+	}
+
+	// 端点恢复一致后再次不一致时重新通知
+	if err := store.ClearDrift("www.example.com", "1.2.3.4:443"); err != nil {
+		t.Fatal(err)
+	}
+	if store.DriftNotified("www.example.com", "1.2.3.4:443", "0b", "0a") {
+		t.Error("清除后的不一致应重新通知")
+	}
+}
+
+func TestOpenCorruptState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	corrupt := []byte(`{"orders": [{"order_id": "order-1"`)
+	if err := os.WriteFile(path, corrupt, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("状态文件损坏时打开失败: %v", err)
+	}
+	if len(s.Orders()) != 0 {
+		t.Errorf("损坏的状态文件应使用空状态库，订单数 = %d", len(s.Orders()))
+	}
+
+	// 损坏的文件保留为备份，原路径可以继续写入
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backups []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "state.json.corrupt-") {
+			backups = append(backups, entry.Name())
+		}
+	}
+	if len(backups) != 1 {
+		t.Fatalf("备份文件 = %v, 期望 1 个", backups)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, backups[0])); err != nil || string(data) != string(corrupt) {
+		t.Errorf("备份内容 = %q, %v", data, err)
+	}
+
+	if err := s.RecordOrder("aliyun", "aliyun", "www.example.com", "order-2", "domain_verify"); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened.Orders()) != 1 {
+		t.Errorf("订单数 = %d, 期望 1", len(reopened.Orders()))
+	}
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFileSync 写入文件并 fsync，确保内容落盘
+func writeFileSync(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeFileAtomic 先写入同目录下的临时文件并 fsync，再重命名到目标路径
+// 崩溃或磁盘写满时目标文件要么是旧内容，要么是完整的新内容
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath, err := writeTempFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// writeTempFile 在 path 所在目录下写入临时文件并 fsync，返回临时文件路径，由调用方重命名或删除
+func writeTempFile(path string, data []byte, perm os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := writeFileSync(tmpPath, data, perm); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
+
+// replaceSymlink 原子地将 linkPath 指向 target：先创建临时链接，再重命名覆盖
+func replaceSymlink(target, linkPath string) error {
+	tmpPath := linkPath + ".tmp"
+	os.Remove(tmpPath)
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, linkPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("替换链接失败: %w", err)
+	}
+	return syncDir(filepath.Dir(linkPath))
+}
+
+// syncDir fsync 目录，确保目录项（新建、重命名）落盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
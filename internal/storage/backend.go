@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/provider"
+)
+
+// Backend 证书存储后端
+type Backend interface {
+	// Name 返回后端名称
+	Name() string
+
+	// SaveCertificate 保存证书，与已保存的证书完全相同时跳过写入并标记为未变化
+	SaveCertificate(domain string, cert *provider.Certificate) (*SaveResult, error)
+
+	// LoadCertificate 读取当前保存的证书
+	LoadCertificate(domain string) (*provider.Certificate, error)
+
+	// ListDomains 列出已保存证书的域名
+	ListDomains() ([]string, error)
+
+	// History 返回域名保存过的证书版本（按保存时间从新到旧）
+	History(domain string) ([]*Version, error)
+
+	// DeleteCertificate 删除域名保存的证书
+	DeleteCertificate(domain string) error
+
+	// Paths 返回证书的本地文件路径，供后置命令使用
+	Paths(domain string) Paths
+}
+
+// Rollbacker 支持回滚到历史版本的存储后端
+type Rollbacker interface {
+	// Rollback 切换到指定的历史版本，version 为空时回滚到当前版本之前的一个版本
+	Rollback(domain, version string) (*SaveResult, error)
+}
+
+// Publisher 需要在启动时重新发布证书文件的存储后端
+// 启用私钥加密时明文私钥只写入解密目录（通常为 tmpfs），重启后需要重新生成
+type Publisher interface {
+	// Publish 按文件布局重新发布域名当前版本的证书文件
+	Publish(domain string) error
+}
+
+// StapleWriter 支持保存 OCSP 响应的存储后端
+type StapleWriter interface {
+	// SaveOCSP 保存域名当前证书的 OCSP 响应（DER 编码）并按文件布局发布
+	SaveOCSP(domain string, response []byte) error
+}
+
+// SaveResult 证书保存结果
+type SaveResult struct {
+	Changed        bool   // 证书是否发生变化（未变化时不会写入）
+	Serial         string // 证书序列号
+	PreviousSerial string // 原证书序列号，之前没有证书时为空
+
+	Metadata provider.Metadata // 保存（或回滚到）的证书的元数据
+}
+
+// Version 已保存的证书版本
+type Version struct {
+	Name      string    // 版本标识（文件存储为归档目录名）
+	Serial    string    // 证书序列号
+	NotBefore time.Time // 生效时间
+	NotAfter  time.Time // 过期时间
+	SavedAt   time.Time // 保存时间
+	Current   bool      // 是否为当前使用的版本
+}
+
+// Paths 证书本地文件路径
+type Paths struct {
+	Dir       string // 证书目录
+	Cert      string // 证书文件
+	Key       string // 私钥文件
+	Fullchain string // 完整证书链文件
+
+	// 额外输出格式的文件，未配置对应格式时为空
+	PKCS12   string // PKCS#12 文件
+	JKS      string // Java KeyStore 文件
+	CertDER  string // DER 编码的证书文件
+	KeyDER   string // DER 编码的私钥文件 (PKCS#8)
+	Combined string // 证书链和私钥合并的 PEM 文件
+	Chain    string // 中间证书文件
+
+	OCSP string // OCSP 响应文件（DER 编码，供 OCSP Stapling 使用），开启 OCSP 检查后生成
+	Meta string // 证书元数据文件 (meta.json)
+}
+
+// compareCertificate 解析新证书并与已保存的证书比较，existing 为 nil 表示之前没有证书
+// 证书指纹、私钥和证书链都相同时标记为未变化
+func compareCertificate(existing, cert *provider.Certificate) (*SaveResult, error) {
+	leaf, err := certutil.ParseCertificatePEM(cert.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书失败: %w", err)
+	}
+
+	result := &SaveResult{
+		Changed:  true,
+		Serial:   certutil.SerialHex(leaf),
+		Metadata: metadataOf(cert),
+	}
+	if existing == nil {
+		return result, nil
+	}
+
+	previous, err := certutil.ParseCertificatePEM(existing.Certificate)
+	if err != nil {
+		return result, nil
+	}
+	result.PreviousSerial = certutil.SerialHex(previous)
+	if certutil.Fingerprint(previous) == certutil.Fingerprint(leaf) &&
+		samePrivateKey(existing.PrivateKey, cert.PrivateKey) &&
+		existing.Chain == fullchain(cert) {
+		result.Changed = false
+	}
+	return result, nil
+}
+
+// samePrivateKey 比较两个 PEM 私钥是否为同一个密钥
+// 按 PKCS#8 DER 比较，PKCS#1/SEC1 格式的私钥加密保存后解密为 PKCS#8 格式，PEM 文本不同但仍是同一个密钥
+func samePrivateKey(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if a == "" || b == "" {
+		return false
+	}
+	keyA, err := certutil.ParsePrivateKeyPEM(a)
+	if err != nil {
+		return false
+	}
+	keyB, err := certutil.ParsePrivateKeyPEM(b)
+	if err != nil {
+		return false
+	}
+	derA, err := x509.MarshalPKCS8PrivateKey(keyA)
+	if err != nil {
+		return false
+	}
+	derB, err := x509.MarshalPKCS8PrivateKey(keyB)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(derA, derB)
+}
+
+// metaFileName 证书元数据文件名
+const metaFileName = "meta.json"
+
+// metadataOf 返回证书的元数据，证书ID和订单ID以外的字段从证书中解析
+func metadataOf(cert *provider.Certificate) provider.Metadata {
+	parsed := *cert
+	parsed.FillMetadata()
+	return parsed.Metadata
+}
+
+// mergeMetadata 证书未变化时沿用已保存的证书ID和订单ID（续期检查时云平台不一定返回）
+func mergeMetadata(meta, existing provider.Metadata) provider.Metadata {
+	if meta.CertID == "" {
+		meta.CertID = existing.CertID
+	}
+	if meta.OrderID == "" {
+		meta.OrderID = existing.OrderID
+	}
+	return meta
+}
+
+// encodeMetadata 将元数据编码为 meta.json 的内容
+func encodeMetadata(meta provider.Metadata) []byte {
+	data, _ := json.MarshalIndent(meta, "", "  ")
+	return append(data, '\n')
+}
+
+// readMetadata 读取 meta.json
+func readMetadata(path string) (provider.Metadata, error) {
+	var meta provider.Metadata
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return meta, nil
+}
+
+// fullchain 返回完整证书链，未提供证书链时使用证书本身
+func fullchain(cert *provider.Certificate) string {
+	if cert.Chain != "" {
+		return cert.Chain
+	}
+	return cert.Certificate
+}
+
+// New 根据配置创建存储后端，domains 提供各域名的额外格式和文件布局配置
+func New(cfg *config.StorageConfig, domains []config.DomainConfig) (Backend, error) {
+	switch cfg.Type {
+	case config.StorageFile:
+		return NewFileStorage(cfg, domains)
+	case config.StorageS3:
+		return NewS3Storage(cfg, domains)
+	case config.StorageVault:
+		return NewVaultStorage(cfg.Vault, cfg.Path, domains)
+	default:
+		return nil, fmt.Errorf("不支持的存储后端: %s", cfg.Type)
+	}
+}
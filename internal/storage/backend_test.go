@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"ssl-manager/internal/provider"
+)
+
+// testCertificate 生成 24 小时有效的自签名测试证书（证书链为叶子证书）
+func testCertificate(t *testing.T, domain string) *provider.Certificate {
+	t.Helper()
+	return testCertificateFor(t, domain, 24*time.Hour)
+}
+
+// testCertificateFor 生成有效期为 validity 的自签名测试证书
+func testCertificateFor(t *testing.T, domain string, validity time.Duration) *provider.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	return &provider.Certificate{
+		Certificate: certPEM,
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		Chain:       certPEM,
+	}
+}
+
+func TestCompareCertificate(t *testing.T) {
+	existing := testCertificate(t, "www.example.com")
+
+	same := *existing
+	result, err := compareCertificate(existing, &same)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Changed {
+		t.Error("相同的证书被标记为已变化")
+	}
+
+	withoutKey := *existing
+	withoutKey.PrivateKey = ""
+	if result, err = compareCertificate(existing, &withoutKey); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Changed {
+		t.Error("缺少私钥的证书被标记为未变化")
+	}
+
+	if result, err = compareCertificate(nil, existing); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Changed || result.PreviousSerial != "" {
+		t.Errorf("首次保存的结果 = %+v", result)
+	}
+}
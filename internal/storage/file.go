@@ -5,79 +5,929 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
 	"ssl-manager/internal/provider"
 )
 
-// FileStorage 文件存储
+// 版本目录布局:
+//
+//	<目录>/<域名>/archive/<序列号>/{cert.pem,key.pem,fullchain.pem}  每次变化保存一个版本
+//	<目录>/<域名>/live -> archive/<序列号>                          指向当前使用的版本
+//	<目录>/<域名>/cert.pem -> live/cert.pem                         兼容旧路径的符号链接
+const (
+	archiveDirName = "archive"
+	liveLinkName   = "live"
+)
+
+// 每个版本保存的文件（另有 formats 配置的额外格式文件）
+var certFileNames = []string{"cert.pem", "key.pem", "fullchain.pem", metaFileName}
+
+// plaintextKeyFiles 启用私钥加密后不在版本目录中保存的明文私钥格式，只在发布时生成
+var plaintextKeyFiles = map[string]bool{
+	keyDERFileName:   true,
+	combinedFileName: true,
+}
+
+// FileStorage 文件存储，证书按版本保存在 <目录>/<域名>/archive/ 下
 type FileStorage struct {
-	baseDir string
+	baseDir    string
+	retention  int
+	settings   domainSettings // 各域名的额外格式和文件布局
+	cipher     keyCipher      // 私钥加密器，未启用加密时为 nil
+	decryptDir string         // 启用加密时写入明文私钥的目录
 }
 
-// NewFileStorage 创建文件存储
-func NewFileStorage(baseDir string) *FileStorage {
-	return &FileStorage{baseDir: baseDir}
+// NewFileStorage 创建文件存储，证书保存在 cfg.Path 下并保留 cfg.Retention 个历史版本
+// domains 提供各域名的额外格式和文件布局配置
+func NewFileStorage(cfg *config.StorageConfig, domains []config.DomainConfig) (*FileStorage, error) {
+	cipher, err := newKeyCipher(cfg.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	storage := &FileStorage{
+		baseDir:   cfg.Path,
+		retention: cfg.Retention,
+		settings:  newDomainSettings(domains),
+		cipher:    cipher,
+	}
+	if cipher != nil {
+		storage.decryptDir = cfg.Encryption.DecryptDir
+	}
+	return storage, nil
 }
 
-// SaveCertificate 保存证书到文件
-func (s *FileStorage) SaveCertificate(domain string, cert *provider.Certificate) error {
-	outputDir := filepath.Join(s.baseDir, domain)
+// Name 返回后端名称
+func (s *FileStorage) Name() string {
+	return config.StorageFile
+}
 
-	// 创建输出目录
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %w", err)
+// SaveCertificate 保存证书为新版本并切换 live 链接
+// 与当前版本的证书指纹、私钥和证书链都相同时跳过写入，并在结果中标记为未变化
+func (s *FileStorage) SaveCertificate(domain string, cert *provider.Certificate) (*SaveResult, error) {
+	paths := s.storePaths(domain)
+
+	// 与当前版本的证书比较
+	existing, _ := s.LoadCertificate(domain)
+	result, err := compareCertificate(existing, cert)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Changed {
+		log.Printf("证书未变化 (序列号: %s)，跳过写入", result.Serial)
+		if result.Metadata, err = s.updateMeta(domain, cert); err != nil {
+			return nil, err
+		}
+		if err := s.refreshCurrent(domain); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// 回滚后云端仍返回被回滚的证书，保持回滚结果，直到签发新证书或手动回滚到新版本
+	// 回滚到的证书进入续期窗口或已过期后不再保持，重新启用被回滚的较新版本
+	if version := s.findRolledBack(domain, cert); version != "" {
+		if !s.keepRollback(domain, existing) {
+			return s.restoreRolledBack(domain, version, result)
+		}
+		log.Printf("证书与已回滚的版本 %s 相同，保持当前版本 (可使用 rollback %s %s 恢复)", version, domain, version)
+		result.Changed = false
+		if err := s.refreshCurrent(domain); err != nil {
+			return nil, err
+		}
+		return result, nil
 	}
 
-	// 保存证书
-	certPath := filepath.Join(outputDir, "cert.pem")
-	if err := os.WriteFile(certPath, []byte(cert.Certificate), 0644); err != nil {
-		return fmt.Errorf("保存证书失败: %w", err)
+	// 旧版本直接保存在域名目录下，先归档以便回滚
+	if err := s.migrateLegacy(domain); err != nil {
+		return nil, fmt.Errorf("归档旧证书失败: %w", err)
 	}
-	log.Printf("  - 证书文件: %s", certPath)
 
-	// 保存私钥
+	version := s.newVersionName(domain, result.Serial)
+	versionDir, err := s.writeVersion(domain, version, cert)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("  - 证书文件: %s", filepath.Join(versionDir, "cert.pem"))
 	if cert.PrivateKey != "" {
-		keyPath := filepath.Join(outputDir, "key.pem")
-		if err := os.WriteFile(keyPath, []byte(cert.PrivateKey), 0600); err != nil {
-			return fmt.Errorf("保存私钥失败: %w", err)
-		}
-		log.Printf("  - 私钥文件: %s", keyPath)
+		log.Printf("  - 私钥文件: %s", filepath.Join(versionDir, "key.pem"))
 	} else {
 		log.Printf("  - 警告: 私钥不可用")
 	}
+	log.Printf("  - 证书链文件: %s", filepath.Join(versionDir, "fullchain.pem"))
+	for _, name := range formatFileNames(s.settings.formats(domain)) {
+		if _, err := os.Stat(filepath.Join(versionDir, name)); err == nil {
+			log.Printf("  - %s", filepath.Join(versionDir, name))
+		}
+	}
+
+	if err := s.activate(domain, version); err != nil {
+		return nil, err
+	}
+	s.prune(domain)
+
+	log.Printf("证书已保存到: %s (版本: %s)", paths.Dir, version)
+	if err := s.publish(domain); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Rollback 将 live 链接切换到指定的历史版本，version 为空时回滚到当前版本之前的一个版本
+// version 可以是版本目录名或证书序列号
+func (s *FileStorage) Rollback(domain, version string) (*SaveResult, error) {
+	versions, err := s.versions(domain)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("域名 %s 没有历史版本", domain)
+	}
+
+	current := s.liveVersion(domain)
+	var target *fileVersion
+	if version == "" {
+		// versions 按保存时间从新到旧排列，取当前版本之后的第一个
+		for i := range versions {
+			if versions[i].name == current && i+1 < len(versions) {
+				target = &versions[i+1]
+				break
+			}
+		}
+		if target == nil {
+			return nil, fmt.Errorf("当前版本 %s 之前没有可回滚的版本", current)
+		}
+	} else {
+		for i := range versions {
+			if versions[i].name == version || versions[i].serial == version {
+				target = &versions[i]
+				break
+			}
+		}
+		if target == nil {
+			return nil, fmt.Errorf("未找到版本 %s", version)
+		}
+	}
+
+	if target.name == current {
+		return nil, fmt.Errorf("版本 %s 已是当前版本", target.name)
+	}
+
+	result := &SaveResult{Changed: true, Serial: target.serial}
+	if cert, err := s.readCertificate(filepath.Join(s.storePaths(domain).Dir, archiveDirName, target.name)); err == nil {
+		result.Metadata = cert.Metadata
+	}
+	for _, v := range versions {
+		if v.name == current {
+			result.PreviousSerial = v.serial
+		}
+	}
 
-	// 保存完整证书链
-	chain := cert.Chain
-	if chain == "" {
-		chain = cert.Certificate
+	// 历史版本可能是在配置新格式之前保存的
+	if err := s.ensureFormats(domain, target.name); err != nil {
+		return nil, err
 	}
-	fullchainPath := filepath.Join(outputDir, "fullchain.pem")
-	if err := os.WriteFile(fullchainPath, []byte(chain), 0644); err != nil {
-		log.Printf("  - 保存证书链失败: %v", err)
+	if err := s.activate(domain, target.name); err != nil {
+		return nil, err
+	}
+	log.Printf("已回滚 %s 到版本 %s (序列号: %s)", domain, target.name, target.serial)
+	if err := s.publish(domain); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LoadCertificate 读取本地保存的证书，加密保存的私钥会被解密
+func (s *FileStorage) LoadCertificate(domain string) (*provider.Certificate, error) {
+	return s.readCertificate(s.storePaths(domain).Dir)
+}
+
+// readCertificate 读取目录（域名目录或版本目录）中的证书，加密保存的私钥会被解密
+func (s *FileStorage) readCertificate(dir string) (*provider.Certificate, error) {
+	certificate, err := os.ReadFile(filepath.Join(dir, "cert.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("读取证书失败: %w", err)
+	}
+
+	key := readFile(filepath.Join(dir, "key.pem"))
+	if key != "" && isEncryptedKey(key) {
+		if s.cipher == nil {
+			return nil, fmt.Errorf("私钥已加密，但未配置 storage.encryption")
+		}
+		if key, err = s.cipher.Decrypt(key); err != nil {
+			return nil, fmt.Errorf("解密私钥失败: %w", err)
+		}
+	}
+
+	cert := &provider.Certificate{
+		Certificate: string(certificate),
+		PrivateKey:  key,
+		Chain:       readFile(filepath.Join(dir, "fullchain.pem")),
+	}
+	// 旧版本没有 meta.json，从证书中解析
+	if meta, err := readMetadata(filepath.Join(dir, metaFileName)); err == nil {
+		cert.Metadata = meta
 	} else {
-		log.Printf("  - 证书链文件: %s", fullchainPath)
+		cert.FillMetadata()
+	}
+	return cert, nil
+}
+
+// updateMeta 证书未变化时更新当前版本的 meta.json（旧版本没有 meta.json，或保存时缺少证书ID和订单ID），返回更新后的元数据
+func (s *FileStorage) updateMeta(domain string, cert *provider.Certificate) (provider.Metadata, error) {
+	meta := metadataOf(cert)
+	version := s.liveVersion(domain)
+	if version == "" {
+		return meta, nil
 	}
 
-	log.Printf("证书已保存到: %s", outputDir)
+	dir := s.storePaths(domain).Dir
+	path := filepath.Join(dir, archiveDirName, version, metaFileName)
+	if existing, err := readMetadata(path); err == nil {
+		meta = mergeMetadata(meta, existing)
+	}
+	data := encodeMetadata(meta)
+	if readFile(path) == string(data) {
+		return meta, nil
+	}
+
+	layout := s.layout(domain)
+	if err := writeFileAtomic(path, data, layout.perm(metaFileName)); err != nil {
+		return meta, fmt.Errorf("保存 %s 失败: %w", metaFileName, err)
+	}
+	if err := layout.apply(path, layout.perm(metaFileName)); err != nil {
+		return meta, err
+	}
+	linkPath := filepath.Join(dir, metaFileName)
+	if info, err := os.Lstat(linkPath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		if err := replaceSymlink(filepath.Join(liveLinkName, metaFileName), linkPath); err != nil {
+			return meta, fmt.Errorf("创建链接 %s 失败: %w", linkPath, err)
+		}
+	}
+	return meta, nil
+}
+
+// ListDomains 列出已保存证书的域名（包含 cert.pem 的子目录）
+func (s *FileStorage) ListDomains() ([]string, error) {
+	entries, err := os.ReadDir(s.baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取证书目录失败: %w", err)
+	}
+
+	var domains []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(s.storePaths(entry.Name()).Cert); err == nil {
+			domains = append(domains, entry.Name())
+		}
+	}
+	return domains, nil
+}
+
+// History 返回域名保存过的证书版本（按保存时间从新到旧）
+func (s *FileStorage) History(domain string) ([]*Version, error) {
+	versions, err := s.versions(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	// 尚未归档的旧布局只有当前版本
+	if len(versions) == 0 {
+		paths := s.storePaths(domain)
+		info, err := os.Stat(paths.Cert)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("读取证书失败: %w", err)
+		}
+		leaf, err := certutil.ParseCertificatePEM(readFile(paths.Cert))
+		if err != nil {
+			return nil, fmt.Errorf("解析证书失败: %w", err)
+		}
+		return []*Version{{
+			Serial:    certutil.SerialHex(leaf),
+			NotBefore: leaf.NotBefore,
+			NotAfter:  leaf.NotAfter,
+			SavedAt:   info.ModTime(),
+			Current:   true,
+		}}, nil
+	}
+
+	current := s.liveVersion(domain)
+	var history []*Version
+	for _, v := range versions {
+		history = append(history, &Version{
+			Name:      v.name,
+			Serial:    v.serial,
+			NotBefore: v.notBefore,
+			NotAfter:  v.notAfter,
+			SavedAt:   v.savedAt,
+			Current:   v.name == current,
+		})
+	}
+	return history, nil
+}
+
+// DeleteCertificate 删除域名的证书目录（以及解密目录中的明文私钥）
+func (s *FileStorage) DeleteCertificate(domain string) error {
+	if err := os.RemoveAll(s.storePaths(domain).Dir); err != nil {
+		return fmt.Errorf("删除证书失败: %w", err)
+	}
+	if s.decryptDir != "" {
+		if err := os.RemoveAll(filepath.Join(s.decryptDir, domain)); err != nil {
+			return fmt.Errorf("删除解密的私钥失败: %w", err)
+		}
+	}
 	return nil
 }
 
-// GetCertDir 获取证书目录
-func (s *FileStorage) GetCertDir(domain string) string {
-	return filepath.Join(s.baseDir, domain)
+// Paths 返回证书文件路径，配置了文件布局时为发布后的路径
+func (s *FileStorage) Paths(domain string) Paths {
+	return s.layout(domain).paths(s.storePaths(domain))
+}
+
+// storePaths 返回存储目录中的证书文件路径
+func (s *FileStorage) storePaths(domain string) Paths {
+	dir := filepath.Join(s.baseDir, domain)
+	paths := Paths{
+		Dir:       dir,
+		Cert:      filepath.Join(dir, "cert.pem"),
+		Key:       filepath.Join(dir, "key.pem"),
+		Fullchain: filepath.Join(dir, "fullchain.pem"),
+		OCSP:      filepath.Join(dir, ocspFileName),
+		Meta:      filepath.Join(dir, metaFileName),
+	}
+	paths.setFormats(s.settings.formats(domain))
+	return paths
+}
+
+// layout 返回域名的文件布局，启用私钥加密时明文私钥发布到解密目录
+func (s *FileStorage) layout(domain string) *fileLayout {
+	layout := s.settings.layout(domain, filepath.Join(s.baseDir, domain))
+	if s.cipher != nil {
+		layout.decryptDir = filepath.Join(s.decryptDir, domain)
+	}
+	return layout
+}
+
+// fileNames 返回域名的所有文件名（包含额外格式）
+func (s *FileStorage) fileNames(domain string) []string {
+	return append(append([]string{}, certFileNames...), formatFileNames(s.settings.formats(domain))...)
 }
 
-// GetCertPath 获取证书路径
-func (s *FileStorage) GetCertPath(domain string) string {
-	return filepath.Join(s.baseDir, domain, "cert.pem")
+// storedNames 返回版本目录中保存的文件名，启用私钥加密时不包含明文私钥格式
+func (s *FileStorage) storedNames(domain string) []string {
+	var names []string
+	for _, name := range s.fileNames(domain) {
+		if s.cipher != nil && plaintextKeyFiles[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
 }
 
-// GetKeyPath 获取私钥路径
-func (s *FileStorage) GetKeyPath(domain string) string {
-	return filepath.Join(s.baseDir, domain, "key.pem")
+// fileVersion 归档目录中的一个版本
+type fileVersion struct {
+	name      string
+	serial    string
+	notBefore time.Time
+	notAfter  time.Time
+	savedAt   time.Time
 }
 
-// GetFullchainPath 获取完整证书链路径
-func (s *FileStorage) GetFullchainPath(domain string) string {
-	return filepath.Join(s.baseDir, domain, "fullchain.pem")
+// versions 列出归档目录中的所有版本（按保存时间从新到旧）
+func (s *FileStorage) versions(domain string) ([]fileVersion, error) {
+	archiveDir := filepath.Join(s.storePaths(domain).Dir, archiveDirName)
+	entries, err := os.ReadDir(archiveDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取归档目录失败: %w", err)
+	}
+
+	var versions []fileVersion
+	for _, entry := range entries {
+		// 跳过写入中断遗留的临时目录
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		certPath := filepath.Join(archiveDir, entry.Name(), "cert.pem")
+		info, err := os.Stat(certPath)
+		if err != nil {
+			continue
+		}
+		leaf, err := certutil.ParseCertificatePEM(readFile(certPath))
+		if err != nil {
+			log.Printf("解析归档证书 %s 失败: %v", certPath, err)
+			continue
+		}
+		versions = append(versions, fileVersion{
+			name:      entry.Name(),
+			serial:    certutil.SerialHex(leaf),
+			notBefore: leaf.NotBefore,
+			notAfter:  leaf.NotAfter,
+			savedAt:   info.ModTime(),
+		})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].savedAt.After(versions[j].savedAt)
+	})
+	return versions, nil
+}
+
+// findRolledBack 查找比当前版本更新（即被回滚掉）且与 cert 相同的版本，没有时返回空字符串
+func (s *FileStorage) findRolledBack(domain string, cert *provider.Certificate) string {
+	versions, err := s.versions(domain)
+	if err != nil {
+		return ""
+	}
+
+	current := s.liveVersion(domain)
+	archiveDir := filepath.Join(s.storePaths(domain).Dir, archiveDirName)
+	for _, v := range versions {
+		// versions 按保存时间从新到旧排列，当前版本之后的都是更旧的版本
+		if v.name == current {
+			break
+		}
+		archived, err := s.readCertificate(filepath.Join(archiveDir, v.name))
+		if err != nil {
+			continue
+		}
+		if result, err := compareCertificate(archived, cert); err == nil && !result.Changed {
+			return v.name
+		}
+	}
+	return ""
+}
+
+// keepRollback 是否保持回滚结果：回滚到的当前证书仍在续期窗口之外且未过期
+func (s *FileStorage) keepRollback(domain string, current *provider.Certificate) bool {
+	if current == nil {
+		return false
+	}
+	leaf, err := certutil.ParseCertificatePEM(current.Certificate)
+	if err != nil || !time.Now().Before(leaf.NotAfter) {
+		return false
+	}
+	daysUntilExpiry := int(time.Until(leaf.NotAfter).Hours() / 24)
+	return daysUntilExpiry > s.settings.renewDays(domain)
+}
+
+// restoreRolledBack 回滚到的证书需要续期时，重新启用与云端证书相同的被回滚版本
+func (s *FileStorage) restoreRolledBack(domain, version string, result *SaveResult) (*SaveResult, error) {
+	log.Printf("回滚到的证书 (序列号: %s) 已进入续期窗口或已过期，重新启用版本 %s", result.PreviousSerial, version)
+	if err := s.ensureFormats(domain, version); err != nil {
+		return nil, err
+	}
+	if err := s.activate(domain, version); err != nil {
+		return nil, err
+	}
+	if err := s.publish(domain); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// liveVersion 返回 live 链接指向的版本名，没有时返回空字符串
+func (s *FileStorage) liveVersion(domain string) string {
+	target, err := os.Readlink(filepath.Join(s.storePaths(domain).Dir, liveLinkName))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// newVersionName 生成新版本的目录名，同一序列号已存在时（如只更换了证书链）追加时间戳
+func (s *FileStorage) newVersionName(domain, serial string) string {
+	name := serial
+	if _, err := os.Stat(filepath.Join(s.storePaths(domain).Dir, archiveDirName, name)); err == nil {
+		name = fmt.Sprintf("%s-%s", serial, time.Now().Format("20060102150405"))
+	}
+	return name
+}
+
+// writeVersion 将证书写入新的版本目录并返回目录路径
+// 文件先写入临时目录并逐个 fsync，全部成功后整体重命名为版本目录，任一文件写入失败则整个版本不生效
+func (s *FileStorage) writeVersion(domain, version string, cert *provider.Certificate) (string, error) {
+	files, err := s.renderStored(domain, cert)
+	if err != nil {
+		return "", err
+	}
+	key := cert.PrivateKey
+	if key != "" && s.cipher != nil {
+		if key, err = s.cipher.Encrypt(key); err != nil {
+			return "", fmt.Errorf("加密私钥失败: %w", err)
+		}
+	}
+
+	archiveDir := filepath.Join(s.storePaths(domain).Dir, archiveDirName)
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return "", fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp(archiveDir, ".tmp-"+version+"-")
+	if err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+	layout := s.layout(domain)
+	if err := layout.apply(tmpDir, layout.dirMode); err != nil {
+		return "", err
+	}
+
+	if err := writeFileSync(filepath.Join(tmpDir, "cert.pem"), []byte(cert.Certificate), layout.perm("cert.pem")); err != nil {
+		return "", fmt.Errorf("保存证书失败: %w", err)
+	}
+	if key != "" {
+		if err := writeFileSync(filepath.Join(tmpDir, "key.pem"), []byte(key), layout.perm("key.pem")); err != nil {
+			return "", fmt.Errorf("保存私钥失败: %w", err)
+		}
+	}
+	if err := writeFileSync(filepath.Join(tmpDir, "fullchain.pem"), []byte(fullchain(cert)), layout.perm("fullchain.pem")); err != nil {
+		return "", fmt.Errorf("保存证书链失败: %w", err)
+	}
+	if err := writeFileSync(filepath.Join(tmpDir, metaFileName), encodeMetadata(metadataOf(cert)), layout.perm(metaFileName)); err != nil {
+		return "", fmt.Errorf("保存 %s 失败: %w", metaFileName, err)
+	}
+	for _, file := range files {
+		if err := writeFileSync(filepath.Join(tmpDir, file.name), file.data, layout.perm(file.name)); err != nil {
+			return "", fmt.Errorf("保存 %s 失败: %w", file.name, err)
+		}
+	}
+	// 在重命名为版本目录之前设置好权限和属主，切换后服务进程即可读取
+	for _, name := range s.storedNames(domain) {
+		path := filepath.Join(tmpDir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := layout.apply(path, layout.perm(name)); err != nil {
+			return "", err
+		}
+	}
+	if err := syncDir(tmpDir); err != nil {
+		return "", fmt.Errorf("同步临时目录失败: %w", err)
+	}
+
+	versionDir := filepath.Join(archiveDir, version)
+	if err := os.Rename(tmpDir, versionDir); err != nil {
+		return "", fmt.Errorf("保存版本目录失败: %w", err)
+	}
+	committed = true
+	if err := syncDir(archiveDir); err != nil {
+		return "", fmt.Errorf("同步归档目录失败: %w", err)
+	}
+	return versionDir, nil
+}
+
+// activate 将 live 链接原子地指向指定版本，并确保域名目录下的兼容链接存在
+func (s *FileStorage) activate(domain, version string) error {
+	dir := s.storePaths(domain).Dir
+
+	if err := replaceSymlink(filepath.Join(archiveDirName, version), filepath.Join(dir, liveLinkName)); err != nil {
+		return fmt.Errorf("切换 live 链接失败: %w", err)
+	}
+
+	for _, name := range s.fileNames(domain) {
+		linkPath := filepath.Join(dir, name)
+		info, err := os.Lstat(linkPath)
+		if s.cipher != nil && plaintextKeyFiles[name] {
+			// 明文私钥格式不在版本目录中，清理启用加密之前创建的链接
+			if err == nil && info.Mode()&os.ModeSymlink != 0 {
+				os.Remove(linkPath)
+			}
+			continue
+		}
+		if err == nil && info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		// 重命名覆盖旧布局下的普通文件，原路径始终可读
+		if err := replaceSymlink(filepath.Join(liveLinkName, name), linkPath); err != nil {
+			return fmt.Errorf("创建链接 %s 失败: %w", linkPath, err)
+		}
+	}
+	return nil
+}
+
+// ensureFormats 为指定版本补充生成缺失的额外格式文件（如版本保存后才配置了新格式）
+func (s *FileStorage) ensureFormats(domain, version string) error {
+	versionDir := filepath.Join(s.storePaths(domain).Dir, archiveDirName, version)
+
+	missing := false
+	for _, name := range formatFileNames(s.settings.formats(domain)) {
+		if s.cipher != nil && plaintextKeyFiles[name] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(versionDir, name)); os.IsNotExist(err) {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	cert, err := s.readCertificate(versionDir)
+	if err != nil {
+		return err
+	}
+	files, err := s.renderStored(domain, cert)
+	if err != nil {
+		return err
+	}
+	layout := s.layout(domain)
+	for _, file := range files {
+		path := filepath.Join(versionDir, file.name)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := writeFileAtomic(path, file.data, layout.perm(file.name)); err != nil {
+			return fmt.Errorf("保存 %s 失败: %w", file.name, err)
+		}
+		if err := layout.apply(path, layout.perm(file.name)); err != nil {
+			return err
+		}
+		log.Printf("已生成证书格式: %s", path)
+	}
+	return nil
+}
+
+// refreshCurrent 证书未变化时同步当前版本：补充缺失的额外格式，按当前配置更新权限和属主并重新发布
+func (s *FileStorage) refreshCurrent(domain string) error {
+	if err := s.ensureCurrentFormats(domain); err != nil {
+		return err
+	}
+	if err := s.encryptVersions(domain); err != nil {
+		return err
+	}
+	version := s.liveVersion(domain)
+	if version == "" {
+		return nil
+	}
+
+	layout := s.layout(domain)
+	versionDir := filepath.Join(s.storePaths(domain).Dir, archiveDirName, version)
+	if err := layout.apply(versionDir, layout.dirMode); err != nil {
+		return err
+	}
+	for _, name := range s.storedNames(domain) {
+		path := filepath.Join(versionDir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := layout.apply(path, layout.perm(name)); err != nil {
+			return err
+		}
+	}
+	return s.publish(domain)
+}
+
+// Publish 按文件布局重新发布当前版本的文件，启用私钥加密时先加密仍为明文的私钥，再将明文私钥写入解密目录
+func (s *FileStorage) Publish(domain string) error {
+	if err := s.encryptVersions(domain); err != nil {
+		return err
+	}
+	if s.liveVersion(domain) == "" {
+		return nil
+	}
+	return s.publish(domain)
+}
+
+// publish 按文件布局将当前版本的文件复制到发布位置
+// 启用私钥加密时私钥在这里解密，key.der 和 combined.pem 也在这里生成
+func (s *FileStorage) publish(domain string) error {
+	liveDir := filepath.Join(s.storePaths(domain).Dir, liveLinkName)
+	layout := s.layout(domain)
+
+	var files []formatFile
+	for _, name := range append(s.storedNames(domain), ocspFileName) {
+		data, err := os.ReadFile(filepath.Join(liveDir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// 切换到新证书后，之前发布的 OCSP 响应属于旧证书，不能继续使用
+				if name == ocspFileName && layout.copied(name) {
+					os.Remove(layout.path(name))
+				}
+				continue
+			}
+			return fmt.Errorf("读取 %s 失败: %w", name, err)
+		}
+		files = append(files, formatFile{name: name, data: data})
+	}
+
+	if s.cipher != nil {
+		cert, err := s.readCertificate(liveDir)
+		if err != nil {
+			return fmt.Errorf("发布证书文件失败: %w", err)
+		}
+		for i := range files {
+			if files[i].name == "key.pem" {
+				files[i].data = []byte(cert.PrivateKey)
+			}
+		}
+		rendered, err := renderFormats(domain, cert, s.settings.formats(domain))
+		if err != nil {
+			return fmt.Errorf("生成证书格式失败: %w", err)
+		}
+		for _, file := range rendered {
+			if plaintextKeyFiles[file.name] {
+				files = append(files, file)
+			}
+		}
+	}
+
+	if err := layout.publish(files); err != nil {
+		return fmt.Errorf("发布证书文件失败: %w", err)
+	}
+	return nil
+}
+
+// SaveOCSP 将 OCSP 响应保存到当前版本目录并发布，域名目录下的 ocsp.der 链接随版本切换
+func (s *FileStorage) SaveOCSP(domain string, response []byte) error {
+	version := s.liveVersion(domain)
+	if version == "" {
+		return fmt.Errorf("域名 %s 没有已保存的证书", domain)
+	}
+
+	dir := s.storePaths(domain).Dir
+	layout := s.layout(domain)
+	path := filepath.Join(dir, archiveDirName, version, ocspFileName)
+	if err := writeFileAtomic(path, response, layout.perm(ocspFileName)); err != nil {
+		return fmt.Errorf("保存 OCSP 响应失败: %w", err)
+	}
+	if err := layout.apply(path, layout.perm(ocspFileName)); err != nil {
+		return err
+	}
+
+	linkPath := filepath.Join(dir, ocspFileName)
+	if info, err := os.Lstat(linkPath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		if err := replaceSymlink(filepath.Join(liveLinkName, ocspFileName), linkPath); err != nil {
+			return fmt.Errorf("创建链接 %s 失败: %w", linkPath, err)
+		}
+	}
+	return s.publish(domain)
+}
+
+// renderStored 生成版本目录中保存的额外格式文件，启用私钥加密时不包含明文私钥格式
+func (s *FileStorage) renderStored(domain string, cert *provider.Certificate) ([]formatFile, error) {
+	rendered, err := renderFormats(domain, cert, s.settings.formats(domain))
+	if err != nil {
+		return nil, fmt.Errorf("生成证书格式失败: %w", err)
+	}
+	var files []formatFile
+	for _, file := range rendered {
+		if s.cipher != nil && plaintextKeyFiles[file.name] {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// encryptVersions 启用私钥加密后加密各版本中仍为明文的私钥，并删除明文私钥格式文件
+func (s *FileStorage) encryptVersions(domain string) error {
+	if s.cipher == nil {
+		return nil
+	}
+	if err := s.migrateLegacy(domain); err != nil {
+		return fmt.Errorf("归档旧证书失败: %w", err)
+	}
+
+	archiveDir := filepath.Join(s.storePaths(domain).Dir, archiveDirName)
+	versions, err := s.versions(domain)
+	if err != nil {
+		return err
+	}
+	layout := s.layout(domain)
+	for _, v := range versions {
+		dir := filepath.Join(archiveDir, v.name)
+		keyPath := filepath.Join(dir, "key.pem")
+		if key := readFile(keyPath); key != "" && !isEncryptedKey(key) {
+			encrypted, err := s.cipher.Encrypt(key)
+			if err != nil {
+				return fmt.Errorf("加密私钥失败: %w", err)
+			}
+			if err := writeFileAtomic(keyPath, []byte(encrypted), layout.perm("key.pem")); err != nil {
+				return fmt.Errorf("保存私钥失败: %w", err)
+			}
+			if err := layout.apply(keyPath, layout.perm("key.pem")); err != nil {
+				return err
+			}
+			log.Printf("已加密私钥: %s", keyPath)
+		}
+		for name := range plaintextKeyFiles {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("删除明文私钥失败: %w", err)
+			}
+		}
+	}
+	if version := s.liveVersion(domain); version != "" {
+		return s.activate(domain, version)
+	}
+	return nil
+}
+
+// ensureCurrentFormats 为当前版本补充生成缺失的额外格式文件并创建对应的链接
+func (s *FileStorage) ensureCurrentFormats(domain string) error {
+	if len(formatFileNames(s.settings.formats(domain))) == 0 {
+		return nil
+	}
+
+	// 旧布局先归档为版本，额外格式只保存在版本目录中
+	if err := s.migrateLegacy(domain); err != nil {
+		return fmt.Errorf("归档旧证书失败: %w", err)
+	}
+	version := s.liveVersion(domain)
+	if version == "" {
+		return nil
+	}
+	if err := s.ensureFormats(domain, version); err != nil {
+		return err
+	}
+	return s.activate(domain, version)
+}
+
+// migrateLegacy 将直接保存在域名目录下的旧版证书文件归档为一个版本
+func (s *FileStorage) migrateLegacy(domain string) error {
+	paths := s.storePaths(domain)
+	info, err := os.Lstat(paths.Cert)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		return nil
+	}
+
+	leaf, err := certutil.ParseCertificatePEM(readFile(paths.Cert))
+	if err != nil {
+		// 无法解析的旧文件不归档，由新版本的链接覆盖
+		log.Printf("无法解析旧证书，跳过归档: %v", err)
+		return nil
+	}
+
+	// 复制而不是移动旧文件，归档完成并切换链接前原路径始终可用
+	legacy := &provider.Certificate{
+		Certificate: readFile(paths.Cert),
+		PrivateKey:  readFile(paths.Key),
+		Chain:       readFile(paths.Fullchain),
+	}
+	version := s.newVersionName(domain, certutil.SerialHex(leaf))
+	if _, err := s.writeVersion(domain, version, legacy); err != nil {
+		return err
+	}
+	log.Printf("旧证书已归档为版本 %s", version)
+	return s.activate(domain, version)
+}
+
+// prune 清理写入中断遗留的临时目录，并按保留数量清理最旧的版本，当前版本始终保留
+func (s *FileStorage) prune(domain string) {
+	archiveDir := filepath.Join(s.storePaths(domain).Dir, archiveDirName)
+	if stale, err := filepath.Glob(filepath.Join(archiveDir, ".tmp-*")); err == nil {
+		for _, dir := range stale {
+			os.RemoveAll(dir)
+		}
+	}
+
+	if s.retention <= 0 {
+		return
+	}
+	versions, err := s.versions(domain)
+	if err != nil || len(versions) <= s.retention {
+		return
+	}
+
+	current := s.liveVersion(domain)
+	for _, v := range versions[s.retention:] {
+		if v.name == current {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(archiveDir, v.name)); err != nil {
+			log.Printf("清理历史版本 %s 失败: %v", v.name, err)
+			continue
+		}
+		log.Printf("已清理历史版本 %s (序列号: %s)", v.name, v.serial)
+	}
+}
+
+// readFile 读取文件内容，文件不存在或读取失败时返回空字符串
+func readFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
 }
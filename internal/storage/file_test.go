@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/provider"
+)
+
+// newTestFileStorage 创建续期天数为 renewDays 的文件存储
+func newTestFileStorage(t *testing.T, domain string, renewDays int) *FileStorage {
+	t.Helper()
+	store, err := NewFileStorage(&config.StorageConfig{Type: config.StorageFile, Path: t.TempDir(), Retention: 5},
+		[]config.DomainConfig{{Domain: domain, RenewDays: renewDays}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// serialOf 返回证书的序列号
+func serialOf(t *testing.T, cert *provider.Certificate) string {
+	t.Helper()
+	leaf, err := certutil.ParseCertificatePEM(cert.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certutil.SerialHex(leaf)
+}
+
+// saveThenRollback 依次保存 older 和 newer，再回滚到 older
+func saveThenRollback(t *testing.T, store *FileStorage, domain string, older, newer *provider.Certificate) {
+	t.Helper()
+	for _, cert := range []*provider.Certificate{older, newer} {
+		if _, err := store.SaveCertificate(domain, cert); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Rollback(domain, serialOf(t, older)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRollbackReleasedInsideRenewWindow(t *testing.T) {
+	const domain = "www.example.com"
+	store := newTestFileStorage(t, domain, 7)
+	older := testCertificateFor(t, domain, 3*24*time.Hour)
+	newer := testCertificateFor(t, domain, 90*24*time.Hour)
+	saveThenRollback(t, store, domain, older, newer)
+
+	// 回滚到的证书已进入续期窗口，云端再次返回较新的证书时重新启用
+	result, err := store.SaveCertificate(domain, newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Changed || result.Serial != serialOf(t, newer) {
+		t.Fatalf("保存结果 = %+v, 期望切换到较新的证书", result)
+	}
+	current, err := store.LoadCertificate(domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Certificate != newer.Certificate {
+		t.Error("当前证书不是较新的证书")
+	}
+	if versions, _ := store.versions(domain); len(versions) != 2 {
+		t.Errorf("版本数 = %d, 应复用已有版本而不是新建", len(versions))
+	}
+}
+
+func TestRollbackKeptOutsideRenewWindow(t *testing.T) {
+	const domain = "www.example.com"
+	store := newTestFileStorage(t, domain, 7)
+	older := testCertificateFor(t, domain, 30*24*time.Hour)
+	newer := testCertificateFor(t, domain, 90*24*time.Hour)
+	saveThenRollback(t, store, domain, older, newer)
+
+	result, err := store.SaveCertificate(domain, newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Changed {
+		t.Fatal("回滚到的证书仍然有效时应保持回滚结果")
+	}
+	if current, err := store.LoadCertificate(domain); err != nil || current.Certificate != older.Certificate {
+		t.Errorf("当前证书应为回滚到的证书: %v", err)
+	}
+}
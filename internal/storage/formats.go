@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"path/filepath"
+
+	keystore "github.com/pavlo-v-chernykh/keystore-go/v4"
+	pkcs12 "software.sslmate.com/src/go-pkcs12"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/provider"
+)
+
+// 额外输出格式的文件名
+const (
+	pkcs12FileName   = "cert.pfx"
+	jksFileName      = "keystore.jks"
+	certDERFileName  = "cert.der"
+	keyDERFileName   = "key.der"
+	combinedFileName = "combined.pem"
+	chainFileName    = "chain.pem"
+	ocspFileName     = "ocsp.der"
+)
+
+// formatFile 按配置生成的额外格式文件
+type formatFile struct {
+	name string
+	data []byte
+}
+
+// formatFileNames 返回配置需要生成的额外格式文件名
+func formatFileNames(formats *config.FormatsConfig) []string {
+	if formats == nil {
+		return nil
+	}
+	var names []string
+	if formats.PKCS12 != nil {
+		names = append(names, pkcs12FileName)
+	}
+	if formats.JKS != nil {
+		names = append(names, jksFileName)
+	}
+	if formats.DER {
+		names = append(names, certDERFileName, keyDERFileName)
+	}
+	if formats.Combined {
+		names = append(names, combinedFileName)
+	}
+	if formats.Chain {
+		names = append(names, chainFileName)
+	}
+	return names
+}
+
+// setFormats 按配置填充额外格式文件的路径
+func (p *Paths) setFormats(formats *config.FormatsConfig) {
+	if formats == nil {
+		return
+	}
+	if formats.PKCS12 != nil {
+		p.PKCS12 = filepath.Join(p.Dir, pkcs12FileName)
+	}
+	if formats.JKS != nil {
+		p.JKS = filepath.Join(p.Dir, jksFileName)
+	}
+	if formats.DER {
+		p.CertDER = filepath.Join(p.Dir, certDERFileName)
+		p.KeyDER = filepath.Join(p.Dir, keyDERFileName)
+	}
+	if formats.Combined {
+		p.Combined = filepath.Join(p.Dir, combinedFileName)
+	}
+	if formats.Chain {
+		p.Chain = filepath.Join(p.Dir, chainFileName)
+	}
+}
+
+// Files 返回文件名（cert.pem、key.pem 等存储后端中的名称）到文件路径的映射，不包含为空的路径
+func (p Paths) Files() map[string]string {
+	files := map[string]string{
+		"cert.pem":       p.Cert,
+		"key.pem":        p.Key,
+		"fullchain.pem":  p.Fullchain,
+		pkcs12FileName:   p.PKCS12,
+		jksFileName:      p.JKS,
+		certDERFileName:  p.CertDER,
+		keyDERFileName:   p.KeyDER,
+		combinedFileName: p.Combined,
+		chainFileName:    p.Chain,
+		ocspFileName:     p.OCSP,
+		metaFileName:     p.Meta,
+	}
+	for name, path := range files {
+		if path == "" {
+			delete(files, name)
+		}
+	}
+	return files
+}
+
+// renderFormats 按配置将证书转换为额外的输出格式
+// 证书没有私钥时跳过需要私钥的格式（PKCS#12、JKS、key.der、combined.pem）
+func renderFormats(domain string, cert *provider.Certificate, formats *config.FormatsConfig) ([]formatFile, error) {
+	if formats == nil {
+		return nil, nil
+	}
+
+	certs, err := certutil.ParseCertificatesPEM(fullchain(cert))
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := certutil.ParseCertificatePEM(cert.Certificate)
+	if err != nil {
+		return nil, err
+	}
+	// 证书链去掉叶子证书后即为中间证书
+	var intermediates []*x509.Certificate
+	for _, c := range certs {
+		if !c.Equal(leaf) {
+			intermediates = append(intermediates, c)
+		}
+	}
+
+	var files []formatFile
+
+	if formats.Chain {
+		var chain bytes.Buffer
+		for _, c := range intermediates {
+			pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+		}
+		files = append(files, formatFile{name: chainFileName, data: chain.Bytes()})
+	}
+	if formats.DER {
+		files = append(files, formatFile{name: certDERFileName, data: leaf.Raw})
+	}
+
+	if cert.PrivateKey == "" {
+		return files, nil
+	}
+	key, err := certutil.ParsePrivateKeyPEM(cert.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("编码私钥失败: %w", err)
+	}
+
+	if formats.DER {
+		files = append(files, formatFile{name: keyDERFileName, data: keyDER})
+	}
+
+	if formats.Combined {
+		combined := fullchain(cert)
+		if len(combined) > 0 && combined[len(combined)-1] != '\n' {
+			combined += "\n"
+		}
+		combined += string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+		files = append(files, formatFile{name: combinedFileName, data: []byte(combined)})
+	}
+
+	if formats.PKCS12 != nil {
+		encoder := pkcs12.Modern
+		if formats.PKCS12.Legacy {
+			encoder = pkcs12.Legacy
+		}
+		pfx, err := encoder.Encode(key, leaf, intermediates, formats.PKCS12.Password)
+		if err != nil {
+			return nil, fmt.Errorf("生成 PKCS#12 失败: %w", err)
+		}
+		files = append(files, formatFile{name: pkcs12FileName, data: pfx})
+	}
+
+	if formats.JKS != nil {
+		alias := formats.JKS.Alias
+		if alias == "" {
+			alias = domain
+		}
+		chain := []keystore.Certificate{{Type: "X509", Content: leaf.Raw}}
+		for _, c := range intermediates {
+			chain = append(chain, keystore.Certificate{Type: "X509", Content: c.Raw})
+		}
+
+		password := []byte(formats.JKS.Password)
+		ks := keystore.New()
+		entry := keystore.PrivateKeyEntry{
+			CreationTime:     leaf.NotBefore,
+			PrivateKey:       keyDER,
+			CertificateChain: chain,
+		}
+		if err := ks.SetPrivateKeyEntry(alias, entry, password); err != nil {
+			return nil, fmt.Errorf("生成 JKS 失败: %w", err)
+		}
+		var jks bytes.Buffer
+		if err := ks.Store(&jks, password); err != nil {
+			return nil, fmt.Errorf("生成 JKS 失败: %w", err)
+		}
+		files = append(files, formatFile{name: jksFileName, data: jks.Bytes()})
+	}
+
+	return files, nil
+}
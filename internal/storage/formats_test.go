@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	keystore "github.com/pavlo-v-chernykh/keystore-go/v4"
+	pkcs12 "software.sslmate.com/src/go-pkcs12"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/provider"
+)
+
+// testChainedCertificate 生成由测试 CA 签发的证书，证书链为叶子证书和 CA 证书，同时返回 CA 证书
+func testChainedCertificate(t *testing.T, domain string) (*provider.Certificate, *x509.Certificate) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Intermediate CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	return &provider.Certificate{
+		Certificate: certPEM,
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		Chain:       certPEM + caPEM,
+	}, ca
+}
+
+// renderedFiles 将 renderFormats 的结果转换为文件名到内容的映射
+func renderedFiles(t *testing.T, domain string, cert *provider.Certificate, formats *config.FormatsConfig) map[string][]byte {
+	t.Helper()
+	files, err := renderFormats(domain, cert, formats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered := map[string][]byte{}
+	for _, file := range files {
+		rendered[file.name] = file.data
+	}
+	return rendered
+}
+
+// pkcs8Key 返回证书私钥的 PKCS#8 DER 编码
+func pkcs8Key(t *testing.T, cert *provider.Certificate) []byte {
+	t.Helper()
+	key, err := certutil.ParsePrivateKeyPEM(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestRenderPKCS12(t *testing.T) {
+	cert, ca := testChainedCertificate(t, "www.example.com")
+	leaf, err := certutil.ParseCertificatePEM(cert.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, legacy := range []bool{false, true} {
+		files := renderedFiles(t, "www.example.com", cert, &config.FormatsConfig{
+			PKCS12: &config.PKCS12Config{Password: "changeit", Legacy: legacy},
+		})
+		pfx, ok := files[pkcs12FileName]
+		if !ok {
+			t.Fatalf("legacy=%v: 没有生成 %s", legacy, pkcs12FileName)
+		}
+
+		key, decodedLeaf, caCerts, err := pkcs12.DecodeChain(pfx, "changeit")
+		if err != nil {
+			t.Fatalf("legacy=%v: 解析 PKCS#12 失败: %v", legacy, err)
+		}
+		if !decodedLeaf.Equal(leaf) {
+			t.Errorf("legacy=%v: PKCS#12 中的证书与叶子证书不一致", legacy)
+		}
+		if len(caCerts) != 1 || !caCerts[0].Equal(ca) {
+			t.Errorf("legacy=%v: PKCS#12 中的中间证书 = %d 个", legacy, len(caCerts))
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil || !bytes.Equal(keyDER, pkcs8Key(t, cert)) {
+			t.Errorf("legacy=%v: PKCS#12 中的私钥与证书私钥不一致", legacy)
+		}
+		if _, _, _, err := pkcs12.DecodeChain(pfx, "wrong"); err == nil {
+			t.Errorf("legacy=%v: 错误的密码也能解析 PKCS#12", legacy)
+		}
+	}
+}
+
+func TestRenderJKS(t *testing.T) {
+	cert, ca := testChainedCertificate(t, "www.example.com")
+	leaf, err := certutil.ParseCertificatePEM(cert.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for alias, formats := range map[string]*config.JKSConfig{
+		"www.example.com": {Password: "changeit"},
+		"tomcat":          {Password: "changeit", Alias: "tomcat"},
+	} {
+		files := renderedFiles(t, "www.example.com", cert, &config.FormatsConfig{JKS: formats})
+		data, ok := files[jksFileName]
+		if !ok {
+			t.Fatalf("没有生成 %s", jksFileName)
+		}
+
+		ks := keystore.New()
+		if err := ks.Load(bytes.NewReader(data), []byte("changeit")); err != nil {
+			t.Fatalf("解析 JKS 失败: %v", err)
+		}
+		entry, err := ks.GetPrivateKeyEntry(alias, []byte("changeit"))
+		if err != nil {
+			t.Fatalf("读取别名 %s 失败: %v (别名: %v)", alias, err, ks.Aliases())
+		}
+		if !bytes.Equal(entry.PrivateKey, pkcs8Key(t, cert)) {
+			t.Error("JKS 中的私钥与证书私钥不一致")
+		}
+		if len(entry.CertificateChain) != 2 ||
+			!bytes.Equal(entry.CertificateChain[0].Content, leaf.Raw) ||
+			!bytes.Equal(entry.CertificateChain[1].Content, ca.Raw) {
+			t.Errorf("JKS 证书链 = %d 个证书，期望叶子证书和中间证书", len(entry.CertificateChain))
+		}
+		if err := keystore.New().Load(bytes.NewReader(data), []byte("wrong")); err == nil {
+			t.Error("错误的密码也能读取 JKS")
+		}
+	}
+}
+
+func TestRenderFormatsWithoutPrivateKey(t *testing.T) {
+	cert, _ := testChainedCertificate(t, "www.example.com")
+	public := &provider.Certificate{Certificate: cert.Certificate, Chain: cert.Chain}
+
+	files := renderedFiles(t, "www.example.com", public, &config.FormatsConfig{
+		PKCS12:   &config.PKCS12Config{Password: "changeit"},
+		JKS:      &config.JKSConfig{Password: "changeit"},
+		DER:      true,
+		Combined: true,
+		Chain:    true,
+	})
+	for _, name := range []string{pkcs12FileName, jksFileName, keyDERFileName, combinedFileName} {
+		if _, ok := files[name]; ok {
+			t.Errorf("没有私钥时不应生成 %s", name)
+		}
+	}
+	for _, name := range []string{certDERFileName, chainFileName} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("没有生成 %s", name)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+
+	"ssl-manager/internal/config"
+)
+
+// testCiphers 返回 pkcs8 和 age 两种加密配置
+func testCiphers(t *testing.T) map[string]*config.EncryptionConfig {
+	t.Helper()
+	dir := t.TempDir()
+	passphraseFile := filepath.Join(dir, "passphrase")
+	if err := os.WriteFile(passphraseFile, []byte("correct horse battery staple\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityFile := filepath.Join(dir, "age.key")
+	if err := os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]*config.EncryptionConfig{
+		config.EncryptionPKCS8: {Type: config.EncryptionPKCS8, PassphraseFile: passphraseFile, DecryptDir: filepath.Join(dir, "decrypted")},
+		config.EncryptionAge: {Type: config.EncryptionAge, Recipients: []string{identity.Recipient().String()},
+			IdentityFile: identityFile, DecryptDir: filepath.Join(dir, "decrypted")},
+	}
+}
+
+func TestKeyCipherRoundTrip(t *testing.T) {
+	cert := testCertificate(t, "www.example.com")
+	for name, cfg := range testCiphers(t) {
+		t.Run(name, func(t *testing.T) {
+			cipher, err := newKeyCipher(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encrypted, err := cipher.Encrypt(cert.PrivateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !isEncryptedKey(encrypted) || strings.Contains(encrypted, "EC PRIVATE KEY") {
+				t.Fatalf("私钥未加密:\n%s", encrypted)
+			}
+			decrypted, err := cipher.Decrypt(encrypted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !samePrivateKey(decrypted, cert.PrivateKey) {
+				t.Error("解密后的私钥与原私钥不一致")
+			}
+			if plain, err := cipher.Decrypt(cert.PrivateKey); err != nil || plain != cert.PrivateKey {
+				t.Errorf("未加密的私钥应原样返回: %v", err)
+			}
+		})
+	}
+}
+
+func TestPKCS8CipherWrongPassphrase(t *testing.T) {
+	cert := testCertificate(t, "www.example.com")
+	encrypted, err := (&pkcs8Cipher{passphrase: "right"}).Encrypt(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&pkcs8Cipher{passphrase: "wrong"}).Decrypt(encrypted); err == nil {
+		t.Fatal("错误的口令应解密失败")
+	}
+}
+
+func TestEncryptedSaveUnchanged(t *testing.T) {
+	for name, cfg := range testCiphers(t) {
+		t.Run(name, func(t *testing.T) {
+			store, err := NewFileStorage(&config.StorageConfig{Type: config.StorageFile, Path: t.TempDir(), Retention: 5, Encryption: cfg}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 提供商返回的是 SEC1 格式 (EC PRIVATE KEY) 的私钥，pkcs8 加密后解密为 PKCS#8 格式
+			cert := testCertificate(t, "www.example.com")
+			if result, err := store.SaveCertificate("www.example.com", cert); err != nil || !result.Changed {
+				t.Fatalf("首次保存: %+v, %v", result, err)
+			}
+			result, err := store.SaveCertificate("www.example.com", cert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Changed {
+				t.Error("同一证书第二次保存被标记为已变化")
+			}
+			if versions, _ := store.versions("www.example.com"); len(versions) != 1 {
+				t.Errorf("版本数 = %d, 期望 1", len(versions))
+			}
+		})
+	}
+}
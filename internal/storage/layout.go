@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"ssl-manager/internal/config"
+)
+
+// secretFileNames 包含私钥的文件，使用 key_mode 权限
+var secretFileNames = map[string]bool{
+	"key.pem":        true,
+	keyDERFileName:   true,
+	combinedFileName: true,
+	pkcs12FileName:   true,
+	jksFileName:      true,
+}
+
+// IsSecretFile 文件是否包含私钥（cert.pem、key.pem 等存储后端中的名称）
+func IsSecretFile(name string) bool {
+	return secretFileNames[name]
+}
+
+// plaintextKeyNames 包含明文私钥的文件，启用私钥加密时默认发布到解密目录
+var plaintextKeyNames = map[string]bool{
+	"key.pem":        true,
+	keyDERFileName:   true,
+	combinedFileName: true,
+}
+
+// domainSettings 域名级别的存储配置（额外格式和文件布局）
+type domainSettings map[string]*config.DomainConfig
+
+// newDomainSettings 按域名索引域名配置
+func newDomainSettings(domains []config.DomainConfig) domainSettings {
+	settings := make(domainSettings, len(domains))
+	for i := range domains {
+		settings[domains[i].Domain] = &domains[i]
+	}
+	return settings
+}
+
+// formats 返回域名额外输出的证书格式
+func (d domainSettings) formats(domain string) *config.FormatsConfig {
+	if cfg, ok := d[domain]; ok {
+		return cfg.Formats
+	}
+	return nil
+}
+
+// renewDays 返回域名的续期天数，未配置的域名返回 0
+func (d domainSettings) renewDays(domain string) int {
+	if cfg, ok := d[domain]; ok {
+		return cfg.RenewDays
+	}
+	return 0
+}
+
+// layout 返回域名的文件布局，domainDir 为存储后端中的域名目录
+func (d domainSettings) layout(domain, domainDir string) *fileLayout {
+	var cfg *config.LayoutConfig
+	if domainCfg, ok := d[domain]; ok {
+		cfg = domainCfg.Layout
+	}
+	layout, err := newFileLayout(domain, domainDir, cfg)
+	if err != nil {
+		// 配置加载时已校验，这里只在用户或用户组被删除等情况下出错
+		log.Printf("域名 %s 的文件布局无效，使用默认布局: %v", domain, err)
+		layout, _ = newFileLayout(domain, domainDir, nil)
+	}
+	return layout
+}
+
+// fileLayout 域名的证书文件布局、属主和权限
+type fileLayout struct {
+	domainDir  string            // 存储后端中的域名目录（版本目录和兼容链接所在）
+	dir        string            // 发布目录
+	names      map[string]string // 文件名 -> 配置的发布文件名或绝对路径
+	decryptDir string            // 启用私钥加密时明文私钥的默认发布目录
+	uid, gid   int               // -1 表示不修改
+	mode       os.FileMode
+	keyMode    os.FileMode
+	dirMode    os.FileMode
+}
+
+// newFileLayout 根据配置生成文件布局，未配置时发布目录即为存储目录
+func newFileLayout(domain, domainDir string, cfg *config.LayoutConfig) (*fileLayout, error) {
+	layout := &fileLayout{
+		domainDir: domainDir,
+		dir:       domainDir,
+		names:     map[string]string{},
+		uid:       -1,
+		gid:       -1,
+		mode:      0644,
+		keyMode:   0600,
+		dirMode:   0755,
+	}
+	if cfg == nil {
+		return layout, nil
+	}
+
+	var err error
+	if layout.mode, layout.keyMode, layout.dirMode, err = cfg.GetModes(); err != nil {
+		return nil, err
+	}
+	if layout.uid, layout.gid, err = cfg.GetOwnership(); err != nil {
+		return nil, err
+	}
+
+	data := map[string]string{"Domain": domain}
+	if cfg.Dir != "" {
+		if layout.dir, err = renderLayoutTemplate(cfg.Dir, data); err != nil {
+			return nil, fmt.Errorf("生成目录失败: %w", err)
+		}
+	}
+	for name, value := range cfg.Files {
+		if layout.names[name], err = renderLayoutTemplate(value, data); err != nil {
+			return nil, fmt.Errorf("生成 %s 文件名失败: %w", name, err)
+		}
+	}
+	return layout, nil
+}
+
+// renderLayoutTemplate 渲染目录或文件名模板
+func renderLayoutTemplate(text string, data map[string]string) (string, error) {
+	tmpl, err := template.New("layout").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// path 返回文件发布后的路径
+// 启用私钥加密时，未单独配置发布位置的明文私钥发布到解密目录，存储目录中只保留密文
+func (l *fileLayout) path(name string) string {
+	if target, ok := l.names[name]; ok {
+		if filepath.IsAbs(target) {
+			return target
+		}
+		target = filepath.Join(l.dir, target)
+		if l.decryptDir != "" && plaintextKeyNames[name] && target == filepath.Join(l.domainDir, name) {
+			return filepath.Join(l.decryptDir, name)
+		}
+		return target
+	}
+	if l.decryptDir != "" && plaintextKeyNames[name] && l.dir == l.domainDir {
+		return filepath.Join(l.decryptDir, name)
+	}
+	return filepath.Join(l.dir, name)
+}
+
+// copied 文件是否需要复制到发布位置（发布路径就是存储目录中的文件时不需要）
+func (l *fileLayout) copied(name string) bool {
+	return l.path(name) != filepath.Join(l.domainDir, name)
+}
+
+// remap 将存储目录中的文件路径映射为发布后的路径，空路径保持为空
+func (l *fileLayout) remap(path string) string {
+	if path == "" {
+		return ""
+	}
+	return l.path(filepath.Base(path))
+}
+
+// paths 将存储目录中的文件路径映射为发布后的路径
+func (l *fileLayout) paths(p Paths) Paths {
+	return Paths{
+		Dir:       l.dir,
+		Cert:      l.remap(p.Cert),
+		Key:       l.remap(p.Key),
+		Fullchain: l.remap(p.Fullchain),
+		PKCS12:    l.remap(p.PKCS12),
+		JKS:       l.remap(p.JKS),
+		CertDER:   l.remap(p.CertDER),
+		KeyDER:    l.remap(p.KeyDER),
+		Combined:  l.remap(p.Combined),
+		Chain:     l.remap(p.Chain),
+		OCSP:      l.remap(p.OCSP),
+		Meta:      l.remap(p.Meta),
+	}
+}
+
+// perm 返回文件权限，包含私钥的文件使用 key_mode
+func (l *fileLayout) perm(name string) os.FileMode {
+	if secretFileNames[name] {
+		return l.keyMode
+	}
+	return l.mode
+}
+
+// apply 设置文件或目录的权限和属主
+func (l *fileLayout) apply(path string, mode os.FileMode) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("设置 %s 权限失败: %w", path, err)
+	}
+	if l.uid >= 0 || l.gid >= 0 {
+		if err := os.Chown(path, l.uid, l.gid); err != nil {
+			return fmt.Errorf("设置 %s 属主失败: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// pendingFile 已写入临时文件、等待重命名到发布位置的文件
+type pendingFile struct {
+	tmp, target string
+}
+
+// publish 将文件写入发布位置并设置权限和属主，内容未变化的文件不会重写
+// 所有变化的文件先写入各自目录下的临时文件并设置好权限和属主，全部成功后再依次重命名，
+// 写入失败时不会发布任何文件，不会出现只替换了部分文件的情况。
+// 多个文件的重命名无法作为整体原子完成，重命名期间读取方仍可能短暂看到新私钥和旧证书：
+// 私钥类文件总是先于证书重命名，证书更新后读到的私钥一定是新的；
+// 需要严格一致的读取方应使用 combined.pem、PKCS#12 等单文件格式，或存储目录中 live 链接指向的版本目录
+func (l *fileLayout) publish(files []formatFile) error {
+	var pending []pendingFile
+	cleanup := func() {
+		for _, p := range pending {
+			os.Remove(p.tmp)
+		}
+	}
+
+	// 私钥类文件排在前面，先于证书重命名
+	ordered := make([]formatFile, 0, len(files))
+	for _, file := range files {
+		if secretFileNames[file.name] {
+			ordered = append(ordered, file)
+		}
+	}
+	for _, file := range files {
+		if !secretFileNames[file.name] {
+			ordered = append(ordered, file)
+		}
+	}
+
+	for _, file := range ordered {
+		name, data := file.name, file.data
+		if !l.copied(name) {
+			continue
+		}
+
+		target := l.path(name)
+		dir := filepath.Dir(target)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			if err := os.MkdirAll(dir, l.dirMode); err != nil {
+				cleanup()
+				return fmt.Errorf("创建目录失败: %w", err)
+			}
+			if err := l.apply(dir, l.dirMode); err != nil {
+				cleanup()
+				return err
+			}
+		}
+
+		perm := l.perm(name)
+		if existing, err := os.ReadFile(target); err == nil && bytes.Equal(existing, data) {
+			if err := l.apply(target, perm); err != nil {
+				cleanup()
+				return err
+			}
+			continue
+		}
+		tmp, err := writeTempFile(target, data, perm)
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("写入 %s 失败: %w", target, err)
+		}
+		pending = append(pending, pendingFile{tmp: tmp, target: target})
+		if err := l.apply(tmp, perm); err != nil {
+			cleanup()
+			return err
+		}
+	}
+
+	dirs := map[string]bool{}
+	for i, p := range pending {
+		if err := os.Rename(p.tmp, p.target); err != nil {
+			for _, rest := range pending[i:] {
+				os.Remove(rest.tmp)
+			}
+			return fmt.Errorf("写入 %s 失败: %w", p.target, err)
+		}
+		dirs[filepath.Dir(p.target)] = true
+		log.Printf("  - 已发布: %s", p.target)
+	}
+	for dir := range dirs {
+		if err := syncDir(dir); err != nil {
+			return fmt.Errorf("同步目录 %s 失败: %w", dir, err)
+		}
+	}
+	return nil
+}
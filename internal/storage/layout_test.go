@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ssl-manager/internal/config"
+)
+
+func TestLayoutPublishAllOrNothing(t *testing.T) {
+	storeDir := filepath.Join(t.TempDir(), "www.example.com")
+	publishDir := t.TempDir()
+	blocker := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	layout, err := newFileLayout("www.example.com", storeDir, &config.LayoutConfig{Dir: publishDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := layout.publish([]formatFile{{name: "cert.pem", data: []byte("cert-1")}, {name: "key.pem", data: []byte("key-1")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 证书的发布位置无法创建时，私钥也不能被替换
+	layout.names["cert.pem"] = filepath.Join(blocker, "cert.pem")
+	err = layout.publish([]formatFile{{name: "key.pem", data: []byte("key-2")}, {name: "cert.pem", data: []byte("cert-2")}})
+	if err == nil {
+		t.Fatal("发布应该失败")
+	}
+	if data, _ := os.ReadFile(filepath.Join(publishDir, "key.pem")); string(data) != "key-1" {
+		t.Errorf("key.pem = %q, 发布失败时不应替换", data)
+	}
+
+	entries, err := os.ReadDir(publishDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("发布失败后残留临时文件: %s", entry.Name())
+		}
+	}
+}
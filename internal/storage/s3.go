@@ -0,0 +1,437 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/provider"
+)
+
+// S3Storage S3 兼容对象存储，证书保存在 <前缀><域名>/ 下
+// 同时在本地缓存目录保留一份，供后置命令使用
+type S3Storage struct {
+	cfg      *config.S3Config
+	endpoint *url.URL
+	prefix   string
+	signer   *sigV4Signer
+	client   *http.Client
+	cache    *FileStorage
+
+	versioningOnce sync.Once
+}
+
+// s3Error S3 错误响应
+type s3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// isNotFound 检查是否为对象不存在错误
+func isNotFound(err error) bool {
+	var s3Err *s3Error
+	return errors.As(err, &s3Err) && s3Err.StatusCode == http.StatusNotFound
+}
+
+// NewS3Storage 创建 S3 兼容对象存储，storageCfg.Path 为本地缓存目录，storageCfg.Retention 为本地缓存保留的版本数
+// domains 中配置的额外格式和文件布局只作用于本地缓存，启用私钥加密时对象存储中的私钥同样加密保存
+func NewS3Storage(storageCfg *config.StorageConfig, domains []config.DomainConfig) (*S3Storage, error) {
+	cfg := storageCfg.S3
+	cache, err := NewFileStorage(storageCfg, domains)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := cfg.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("无效的 S3 endpoint: %s", cfg.Endpoint)
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &S3Storage{
+		cfg:      cfg,
+		endpoint: u,
+		prefix:   prefix,
+		signer: &sigV4Signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			sessionToken:    cfg.SessionToken,
+			region:          region,
+			now:             time.Now,
+		},
+		client: &http.Client{Timeout: 30 * time.Second},
+		cache:  cache,
+	}, nil
+}
+
+// Name 返回后端名称
+func (s *S3Storage) Name() string {
+	return config.StorageS3
+}
+
+// SaveCertificate 上传证书到对象存储并同步到本地缓存
+// 与对象存储中已有的证书完全相同时跳过上传，并在结果中标记为未变化
+func (s *S3Storage) SaveCertificate(domain string, cert *provider.Certificate) (*SaveResult, error) {
+	s.versioningOnce.Do(func() {
+		if s.cfg.Versioning {
+			if err := s.enableVersioning(); err != nil {
+				log.Printf("开启存储桶版本控制失败: %v", err)
+			}
+		}
+	})
+
+	existing, err := s.LoadCertificate(domain)
+	if err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("读取已有证书失败: %w", err)
+	}
+	result, err := compareCertificate(existing, cert)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Changed {
+		result.Metadata = mergeMetadata(result.Metadata, existing.Metadata)
+	}
+	meta := encodeMetadata(result.Metadata)
+
+	if result.Changed {
+		// 证书最后上传，读取方看到新证书时私钥和证书链已经就绪
+		if cert.PrivateKey != "" {
+			key := cert.PrivateKey
+			if s.cache.cipher != nil {
+				if key, err = s.cache.cipher.Encrypt(key); err != nil {
+					return nil, fmt.Errorf("加密私钥失败: %w", err)
+				}
+			}
+			if err := s.putObject(s.objectKey(domain, "key.pem"), []byte(key)); err != nil {
+				return nil, fmt.Errorf("上传私钥失败: %w", err)
+			}
+		} else {
+			log.Printf("  - 警告: 私钥不可用")
+		}
+		if err := s.putObject(s.objectKey(domain, "fullchain.pem"), []byte(fullchain(cert))); err != nil {
+			return nil, fmt.Errorf("上传证书链失败: %w", err)
+		}
+		if err := s.putObject(s.objectKey(domain, metaFileName), meta); err != nil {
+			return nil, fmt.Errorf("上传 %s 失败: %w", metaFileName, err)
+		}
+		if err := s.putObject(s.objectKey(domain, "cert.pem"), []byte(cert.Certificate)); err != nil {
+			return nil, fmt.Errorf("上传证书失败: %w", err)
+		}
+		log.Printf("证书已上传到: s3://%s/%s%s/", s.cfg.Bucket, s.prefix, domain)
+	} else {
+		log.Printf("对象存储中的证书未变化 (序列号: %s)，跳过上传", result.Serial)
+		// 之前保存的证书没有 meta.json，或者这次补充了证书ID和订单ID
+		if string(encodeMetadata(existing.Metadata)) != string(meta) {
+			if err := s.putObject(s.objectKey(domain, metaFileName), meta); err != nil {
+				return nil, fmt.Errorf("上传 %s 失败: %w", metaFileName, err)
+			}
+		}
+	}
+
+	// 本地缓存可能因容器重建而丢失，每次都同步
+	if _, err := s.cache.SaveCertificate(domain, cert); err != nil {
+		return nil, fmt.Errorf("同步本地缓存失败: %w", err)
+	}
+	return result, nil
+}
+
+// LoadCertificate 从对象存储读取证书
+func (s *S3Storage) LoadCertificate(domain string) (*provider.Certificate, error) {
+	certificate, err := s.getObject(s.objectKey(domain, "cert.pem"), "")
+	if err != nil {
+		return nil, fmt.Errorf("读取证书失败: %w", err)
+	}
+
+	cert := &provider.Certificate{Certificate: certificate}
+	if cert.PrivateKey, err = s.getObject(s.objectKey(domain, "key.pem"), ""); err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("读取私钥失败: %w", err)
+	}
+	if cert.PrivateKey != "" && isEncryptedKey(cert.PrivateKey) {
+		if s.cache.cipher == nil {
+			return nil, fmt.Errorf("私钥已加密，但未配置 storage.encryption")
+		}
+		if cert.PrivateKey, err = s.cache.cipher.Decrypt(cert.PrivateKey); err != nil {
+			return nil, fmt.Errorf("解密私钥失败: %w", err)
+		}
+	}
+	if cert.Chain, err = s.getObject(s.objectKey(domain, "fullchain.pem"), ""); err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("读取证书链失败: %w", err)
+	}
+
+	// 旧版本没有上传 meta.json，从证书中解析
+	data, err := s.getObject(s.objectKey(domain, metaFileName), "")
+	if err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("读取 %s 失败: %w", metaFileName, err)
+	}
+	if data == "" || json.Unmarshal([]byte(data), &cert.Metadata) != nil {
+		cert.FillMetadata()
+	}
+	return cert, nil
+}
+
+// ListDomains 列出对象存储中已保存证书的域名
+func (s *S3Storage) ListDomains() ([]string, error) {
+	var domains []string
+	token := ""
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"prefix":    {s.prefix},
+			"delimiter": {"/"},
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		body, err := s.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("列出对象失败: %w", err)
+		}
+
+		var result struct {
+			CommonPrefixes []struct {
+				Prefix string `xml:"Prefix"`
+			} `xml:"CommonPrefixes"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("解析对象列表失败: %w", err)
+		}
+		for _, prefix := range result.CommonPrefixes {
+			domain := strings.TrimSuffix(strings.TrimPrefix(prefix.Prefix, s.prefix), "/")
+			if domain != "" {
+				domains = append(domains, domain)
+			}
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return domains, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// History 返回对象存储中证书的所有版本（需要存储桶开启版本控制）
+func (s *S3Storage) History(domain string) ([]*Version, error) {
+	key := s.objectKey(domain, "cert.pem")
+
+	type objectVersion struct {
+		Key          string    `xml:"Key"`
+		VersionID    string    `xml:"VersionId"`
+		IsLatest     bool      `xml:"IsLatest"`
+		LastModified time.Time `xml:"LastModified"`
+	}
+
+	var objects []objectVersion
+	keyMarker, versionMarker := "", ""
+	for {
+		query := url.Values{
+			"versions": {""},
+			"prefix":   {key},
+		}
+		if keyMarker != "" {
+			query.Set("key-marker", keyMarker)
+			query.Set("version-id-marker", versionMarker)
+		}
+
+		body, err := s.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("列出对象版本失败: %w", err)
+		}
+
+		var result struct {
+			Versions            []objectVersion `xml:"Version"`
+			IsTruncated         bool            `xml:"IsTruncated"`
+			NextKeyMarker       string          `xml:"NextKeyMarker"`
+			NextVersionIDMarker string          `xml:"NextVersionIdMarker"`
+		}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("解析对象版本失败: %w", err)
+		}
+		for _, version := range result.Versions {
+			if version.Key == key {
+				objects = append(objects, version)
+			}
+		}
+
+		if !result.IsTruncated || result.NextKeyMarker == "" {
+			break
+		}
+		keyMarker, versionMarker = result.NextKeyMarker, result.NextVersionIDMarker
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].LastModified.After(objects[j].LastModified)
+	})
+
+	var versions []*Version
+	for _, object := range objects {
+		data, err := s.getObject(key, object.VersionID)
+		if err != nil {
+			return nil, fmt.Errorf("读取证书版本 %s 失败: %w", object.VersionID, err)
+		}
+		leaf, err := certutil.ParseCertificatePEM(data)
+		if err != nil {
+			log.Printf("解析证书版本 %s 失败: %v", object.VersionID, err)
+			continue
+		}
+		versions = append(versions, &Version{
+			Serial:    certutil.SerialHex(leaf),
+			NotBefore: leaf.NotBefore,
+			NotAfter:  leaf.NotAfter,
+			SavedAt:   object.LastModified,
+			Current:   object.IsLatest,
+		})
+	}
+	return versions, nil
+}
+
+// DeleteCertificate 删除对象存储和本地缓存中的证书
+// 存储桶开启版本控制时只会添加删除标记，历史版本仍然保留
+func (s *S3Storage) DeleteCertificate(domain string) error {
+	for _, name := range []string{"cert.pem", "key.pem", "fullchain.pem", metaFileName} {
+		if _, err := s.do(http.MethodDelete, s.objectKey(domain, name), nil, nil, nil); err != nil && !isNotFound(err) {
+			return fmt.Errorf("删除对象 %s 失败: %w", name, err)
+		}
+	}
+	return s.cache.DeleteCertificate(domain)
+}
+
+// Paths 返回本地缓存中的证书文件路径
+func (s *S3Storage) Paths(domain string) Paths {
+	return s.cache.Paths(domain)
+}
+
+// SaveOCSP 将 OCSP 响应保存到本地缓存并发布（OCSP 响应可以随时重新获取，不上传到对象存储）
+func (s *S3Storage) SaveOCSP(domain string, response []byte) error {
+	return s.cache.SaveOCSP(domain, response)
+}
+
+// Publish 按文件布局重新发布本地缓存中的证书文件
+func (s *S3Storage) Publish(domain string) error {
+	return s.cache.Publish(domain)
+}
+
+// enableVersioning 为存储桶开启版本控制
+func (s *S3Storage) enableVersioning() error {
+	body := []byte(`<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Status>Enabled</Status></VersioningConfiguration>`)
+	_, err := s.do(http.MethodPut, "", url.Values{"versioning": {""}}, body, nil)
+	return err
+}
+
+// objectKey 生成对象名
+func (s *S3Storage) objectKey(domain, name string) string {
+	return s.prefix + domain + "/" + name
+}
+
+// getObject 读取对象内容，versionID 为空时读取最新版本
+func (s *S3Storage) getObject(key, versionID string) (string, error) {
+	var query url.Values
+	if versionID != "" {
+		query = url.Values{"versionId": {versionID}}
+	}
+	body, err := s.do(http.MethodGet, key, query, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// putObject 上传对象，配置了服务端加密时附加加密请求头
+func (s *S3Storage) putObject(key string, data []byte) error {
+	headers := map[string]string{"Content-Type": "application/x-pem-file"}
+	if s.cfg.SSE != "" {
+		headers["X-Amz-Server-Side-Encryption"] = s.cfg.SSE
+		if s.cfg.SSEKMSKeyID != "" {
+			headers["X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"] = s.cfg.SSEKMSKeyID
+		}
+	}
+	_, err := s.do(http.MethodPut, key, nil, data, headers)
+	return err
+}
+
+// do 发送签名请求并返回响应体，非 2xx 响应返回 *s3Error
+func (s *S3Storage) do(method, key string, query url.Values, body []byte, headers map[string]string) ([]byte, error) {
+	u := *s.endpoint
+	path := "/" + key
+	if s.cfg.PathStyle {
+		path = "/" + s.cfg.Bucket + path
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	u.Path = path
+	u.RawPath = uriEncode(path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if len(body) > 0 {
+		sum := md5.Sum(body)
+		req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	}
+
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		payloadHash = sha256Hex(body)
+	}
+	s.signer.Sign(req, payloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		s3Err := &s3Error{StatusCode: resp.StatusCode}
+		xml.Unmarshal(data, s3Err)
+		return nil, s3Err
+	}
+	return data, nil
+}
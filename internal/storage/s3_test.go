@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ssl-manager/internal/config"
+)
+
+// fakeS3 测试用 S3 服务，按 SigV4 校验每个请求的签名（不匹配时返回 SignatureDoesNotMatch），对象保存在内存中（只支持路径风格）
+type fakeS3 struct {
+	bucket  string
+	keyID   string
+	secret  string
+	region  string
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if msg := f.verify(r, body); msg != "" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>%s</Message></Error>", msg)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "<Error><Code>NoSuchBucket</Code></Error>")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		prefix := r.URL.Query().Get("prefix")
+		prefixes := map[string]bool{}
+		for name := range f.objects {
+			rest, ok := strings.CutPrefix(name, prefix)
+			if !ok {
+				continue
+			}
+			if dir, _, found := strings.Cut(rest, "/"); found {
+				prefixes[prefix+dir+"/"] = true
+			}
+		}
+		var names []string
+		for name := range prefixes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprint(w, "<ListBucketResult>")
+		for _, name := range names {
+			fmt.Fprintf(w, "<CommonPrefixes><Prefix>%s</Prefix></CommonPrefixes>", name)
+		}
+		fmt.Fprint(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>")
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify 按服务端收到的请求重新计算签名，返回不匹配的原因
+func (f *fakeS3) verify(r *http.Request, body []byte) string {
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != sha256Hex(body) {
+		return "x-amz-content-sha256 与请求体不一致"
+	}
+	if len(body) > 0 {
+		sum := md5.Sum(body)
+		if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+			return "Content-MD5 与请求体不一致"
+		}
+	}
+	now, err := time.Parse(sigV4TimeFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return "无效的 X-Amz-Date"
+	}
+
+	received := r.Clone(r.Context())
+	received.Header.Del("Authorization")
+	signer := &sigV4Signer{accessKeyID: f.keyID, secretAccessKey: f.secret, region: f.region, now: func() time.Time { return now }}
+	signer.Sign(received, payloadHash)
+	if got, want := r.Header.Get("Authorization"), received.Header.Get("Authorization"); got != want {
+		return fmt.Sprintf("Authorization = %s, 期望 %s", got, want)
+	}
+	return ""
+}
+
+// newFakeS3Storage 启动 fakeS3 并创建指向它的 S3 存储，secret 为客户端使用的访问密钥
+func newFakeS3Storage(t *testing.T, secret string) (*S3Storage, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{bucket: "certs", keyID: "AKIDEXAMPLE", secret: "secret", region: "cn-test-1", objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Storage(&config.StorageConfig{
+		Type:      config.StorageS3,
+		Path:      t.TempDir(),
+		Retention: 5,
+		S3: &config.S3Config{
+			Endpoint:        server.URL,
+			Region:          fake.region,
+			Bucket:          fake.bucket,
+			Prefix:          "ssl",
+			AccessKeyID:     fake.keyID,
+			SecretAccessKey: secret,
+			PathStyle:       true,
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, fake
+}
+
+func TestS3StorageRoundTrip(t *testing.T) {
+	store, fake := newFakeS3Storage(t, "secret")
+	cert := testCertificate(t, "*.example.com")
+
+	result, err := store.SaveCertificate("*.example.com", cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Changed {
+		t.Error("首次保存应标记为已变化")
+	}
+	if _, ok := fake.objects["ssl/*.example.com/cert.pem"]; !ok {
+		t.Errorf("对象存储中缺少证书: %v", fake.objects)
+	}
+
+	loaded, err := store.LoadCertificate("*.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Certificate != cert.Certificate || loaded.PrivateKey != cert.PrivateKey || loaded.Chain != cert.Chain {
+		t.Error("读取的证书与保存的不一致")
+	}
+	if result, err := store.SaveCertificate("*.example.com", cert); err != nil || result.Changed {
+		t.Errorf("重复保存: changed = %v, err = %v", result != nil && result.Changed, err)
+	}
+
+	domains, err := store.ListDomains()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 1 || domains[0] != "*.example.com" {
+		t.Errorf("ListDomains = %v", domains)
+	}
+
+	if err := store.DeleteCertificate("*.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.LoadCertificate("*.example.com"); !isNotFound(err) {
+		t.Errorf("删除后读取: err = %v", err)
+	}
+}
+
+func TestS3StorageSignatureMismatch(t *testing.T) {
+	store, _ := newFakeS3Storage(t, "wrong-secret")
+	_, err := store.LoadCertificate("www.example.com")
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("错误的密钥: err = %v", err)
+	}
+}
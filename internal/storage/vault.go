@@ -0,0 +1,584 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+	"ssl-manager/internal/provider"
+)
+
+// Kubernetes ServiceAccount token 默认路径
+const defaultKubernetesJWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultStorage HashiCorp Vault KV v2 存储
+// 证书、证书链和私钥只保存在 Vault 中；本地缓存目录只保留证书和证书链供后置命令使用，不落盘私钥
+type VaultStorage struct {
+	cfg      *config.VaultConfig
+	path     *template.Template
+	client   *http.Client
+	cache    string
+	settings domainSettings // 各域名的额外格式（只支持不含私钥的格式）和文件布局
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time // 为零表示不过期（静态 token）
+}
+
+// vaultError Vault 错误响应
+type vaultError struct {
+	StatusCode int
+	Errors     []string `json:"errors"`
+}
+
+func (e *vaultError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// isVaultNotFound 检查是否为路径不存在错误
+func isVaultNotFound(err error) bool {
+	var vaultErr *vaultError
+	return errors.As(err, &vaultErr) && vaultErr.StatusCode == http.StatusNotFound
+}
+
+// vaultSecret KV v2 读取响应中的 data 字段
+type vaultSecret struct {
+	Data     map[string]string `json:"data"`
+	Metadata struct {
+		Version     int       `json:"version"`
+		CreatedTime time.Time `json:"created_time"`
+	} `json:"metadata"`
+}
+
+// vaultMetadata KV v2 元数据
+type vaultMetadata struct {
+	CurrentVersion int                          `json:"current_version"`
+	CustomMetadata map[string]string            `json:"custom_metadata"`
+	Versions       map[string]vaultVersionState `json:"versions"`
+}
+
+// vaultVersionState KV v2 单个版本的状态
+type vaultVersionState struct {
+	CreatedTime  time.Time `json:"created_time"`
+	DeletionTime string    `json:"deletion_time"`
+	Destroyed    bool      `json:"destroyed"`
+}
+
+// NewVaultStorage 创建 Vault KV v2 存储，cacheDir 为本地缓存目录
+func NewVaultStorage(cfg *config.VaultConfig, cacheDir string, domains []config.DomainConfig) (*VaultStorage, error) {
+	pathTemplate := cfg.Path
+	if pathTemplate == "" {
+		pathTemplate = "ssl-manager/{{.Domain}}"
+	}
+	tmpl, err := template.New("vault-path").Option("missingkey=error").Parse(pathTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("解析 Vault 路径模板失败: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CACert != "" {
+		pemData, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("读取 Vault CA 证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("Vault CA 证书无效: %s", cfg.CACert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &VaultStorage{
+		cfg:      cfg,
+		path:     tmpl,
+		client:   &http.Client{Timeout: 30 * time.Second, Transport: transport},
+		cache:    cacheDir,
+		settings: newDomainSettings(domains),
+	}, nil
+}
+
+// Name 返回后端名称
+func (s *VaultStorage) Name() string {
+	return config.StorageVault
+}
+
+// SaveCertificate 写入证书到 Vault，并在自定义元数据中记录序列号和有效期
+// 与 Vault 中已有的证书完全相同时跳过写入，并在结果中标记为未变化
+func (s *VaultStorage) SaveCertificate(domain string, cert *provider.Certificate) (*SaveResult, error) {
+	secretPath, err := s.secretPath(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.LoadCertificate(domain)
+	if err != nil && !isVaultNotFound(err) {
+		return nil, fmt.Errorf("读取已有证书失败: %w", err)
+	}
+	result, err := compareCertificate(existing, cert)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Changed {
+		result.Metadata = mergeMetadata(result.Metadata, existing.Metadata)
+	}
+
+	if result.Changed {
+		data := map[string]interface{}{
+			"data": map[string]string{
+				"certificate": cert.Certificate,
+				"private_key": cert.PrivateKey,
+				"chain":       fullchain(cert),
+				"cert_id":     cert.CertID,
+				"order_id":    cert.OrderID,
+			},
+		}
+		if _, err := s.do(http.MethodPost, s.apiPath("data", secretPath), data); err != nil {
+			return nil, fmt.Errorf("写入 Vault 失败: %w", err)
+		}
+
+		leaf, _ := certutil.ParseCertificatePEM(cert.Certificate)
+		metadata := map[string]interface{}{
+			"custom_metadata": map[string]string{
+				"domain":      domain,
+				"serial":      certutil.SerialHex(leaf),
+				"fingerprint": certutil.Fingerprint(leaf),
+				"issuer":      result.Metadata.Issuer,
+				"key_type":    result.Metadata.KeyType,
+				"cert_id":     cert.CertID,
+				"not_before":  leaf.NotBefore.UTC().Format(time.RFC3339),
+				"not_after":   leaf.NotAfter.UTC().Format(time.RFC3339),
+			},
+		}
+		if _, err := s.do(http.MethodPost, s.apiPath("metadata", secretPath), metadata); err != nil {
+			// 元数据只用于展示和检索，写入失败不影响证书本身
+			log.Printf("写入 Vault 自定义元数据失败: %v", err)
+		}
+		log.Printf("证书已写入 Vault: %s/%s", s.mount(), secretPath)
+	} else {
+		log.Printf("Vault 中的证书未变化 (序列号: %s)，跳过写入", result.Serial)
+	}
+
+	if err := s.writeCache(domain, cert, result.Metadata); err != nil {
+		return nil, fmt.Errorf("同步本地缓存失败: %w", err)
+	}
+	return result, nil
+}
+
+// LoadCertificate 从 Vault 读取当前版本的证书
+func (s *VaultStorage) LoadCertificate(domain string) (*provider.Certificate, error) {
+	secret, err := s.readSecret(domain, 0)
+	if err != nil {
+		return nil, fmt.Errorf("读取证书失败: %w", err)
+	}
+	cert := &provider.Certificate{
+		Certificate: secret.Data["certificate"],
+		PrivateKey:  secret.Data["private_key"],
+		Chain:       secret.Data["chain"],
+	}
+	cert.CertID = secret.Data["cert_id"]
+	cert.OrderID = secret.Data["order_id"]
+	cert.FillMetadata()
+	return cert, nil
+}
+
+// ListDomains 列出 Vault 中已保存证书的域名，要求路径模板以 {{.Domain}} 结尾
+func (s *VaultStorage) ListDomains() ([]string, error) {
+	pathTemplate := s.path.Root.String()
+	prefix, suffix, found := strings.Cut(pathTemplate, "{{.Domain}}")
+	if !found || suffix != "" || strings.Contains(prefix, "{{") {
+		return nil, fmt.Errorf("路径模板 %s 不支持列出域名", pathTemplate)
+	}
+
+	resp, err := s.do(http.MethodGet, s.apiPath("metadata", strings.TrimSuffix(prefix, "/"))+"?list=true", nil)
+	if err != nil {
+		if isVaultNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("列出 Vault 路径失败: %w", err)
+	}
+
+	var list struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal(resp, &list); err != nil {
+		return nil, fmt.Errorf("解析 Vault 响应失败: %w", err)
+	}
+
+	var domains []string
+	for _, key := range list.Keys {
+		if !strings.HasSuffix(key, "/") {
+			domains = append(domains, key)
+		}
+	}
+	return domains, nil
+}
+
+// History 返回 Vault 中保留的所有证书版本（按版本从新到旧，跳过已删除的版本）
+func (s *VaultStorage) History(domain string) ([]*Version, error) {
+	secretPath, err := s.secretPath(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(http.MethodGet, s.apiPath("metadata", secretPath), nil)
+	if err != nil {
+		if isVaultNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取 Vault 元数据失败: %w", err)
+	}
+	var metadata vaultMetadata
+	if err := json.Unmarshal(resp, &metadata); err != nil {
+		return nil, fmt.Errorf("解析 Vault 元数据失败: %w", err)
+	}
+
+	var numbers []int
+	for key, state := range metadata.Versions {
+		number, err := strconv.Atoi(key)
+		if err != nil || state.Destroyed || state.DeletionTime != "" {
+			continue
+		}
+		numbers = append(numbers, number)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(numbers)))
+
+	var versions []*Version
+	for _, number := range numbers {
+		secret, err := s.readSecret(domain, number)
+		if err != nil {
+			return nil, fmt.Errorf("读取证书版本 %d 失败: %w", number, err)
+		}
+		leaf, err := certutil.ParseCertificatePEM(secret.Data["certificate"])
+		if err != nil {
+			log.Printf("解析证书版本 %d 失败: %v", number, err)
+			continue
+		}
+		versions = append(versions, &Version{
+			Serial:    certutil.SerialHex(leaf),
+			NotBefore: leaf.NotBefore,
+			NotAfter:  leaf.NotAfter,
+			SavedAt:   secret.Metadata.CreatedTime,
+			Current:   number == metadata.CurrentVersion,
+		})
+	}
+	return versions, nil
+}
+
+// DeleteCertificate 删除 Vault 中证书的当前版本（可通过 Vault 恢复）以及本地缓存
+func (s *VaultStorage) DeleteCertificate(domain string) error {
+	secretPath, err := s.secretPath(domain)
+	if err != nil {
+		return err
+	}
+	if _, err := s.do(http.MethodDelete, s.apiPath("data", secretPath), nil); err != nil && !isVaultNotFound(err) {
+		return fmt.Errorf("删除 Vault 证书失败: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Join(s.cache, domain)); err != nil {
+		return fmt.Errorf("删除本地缓存失败: %w", err)
+	}
+	return nil
+}
+
+// Paths 返回本地缓存中的证书文件路径（配置了文件布局时为发布后的路径），私钥不落盘，Key 和 KeyDER 为空
+func (s *VaultStorage) Paths(domain string) Paths {
+	return s.layout(domain).paths(s.cachePaths(domain))
+}
+
+// cachePaths 返回本地缓存目录中的证书文件路径
+func (s *VaultStorage) cachePaths(domain string) Paths {
+	dir := filepath.Join(s.cache, domain)
+	paths := Paths{
+		Dir:       dir,
+		Cert:      filepath.Join(dir, "cert.pem"),
+		Fullchain: filepath.Join(dir, "fullchain.pem"),
+		OCSP:      filepath.Join(dir, ocspFileName),
+		Meta:      filepath.Join(dir, metaFileName),
+	}
+	paths.setFormats(s.settings.formats(domain))
+	paths.KeyDER = ""
+	return paths
+}
+
+// SaveOCSP 将 OCSP 响应保存到本地缓存并按文件布局发布
+func (s *VaultStorage) SaveOCSP(domain string, response []byte) error {
+	paths := s.cachePaths(domain)
+	layout := s.layout(domain)
+	if err := writeFileAtomic(paths.OCSP, response, layout.perm(ocspFileName)); err != nil {
+		return fmt.Errorf("保存 OCSP 响应失败: %w", err)
+	}
+	if err := layout.apply(paths.OCSP, layout.perm(ocspFileName)); err != nil {
+		return err
+	}
+	if err := layout.publish([]formatFile{{name: ocspFileName, data: response}}); err != nil {
+		return fmt.Errorf("发布 OCSP 响应失败: %w", err)
+	}
+	return nil
+}
+
+// layout 返回域名的文件布局
+func (s *VaultStorage) layout(domain string) *fileLayout {
+	return s.settings.layout(domain, filepath.Join(s.cache, domain))
+}
+
+// writeCache 将证书、证书链和元数据写入本地缓存并按文件布局发布，同时清理之前可能遗留的私钥文件
+func (s *VaultStorage) writeCache(domain string, cert *provider.Certificate, meta provider.Metadata) error {
+	paths := s.cachePaths(domain)
+	if err := os.MkdirAll(paths.Dir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	layout := s.layout(domain)
+	if readFile(paths.Cert) != cert.Certificate {
+		// 之前的 OCSP 响应属于旧证书，不能继续使用
+		os.Remove(paths.OCSP)
+		if layout.copied(ocspFileName) {
+			os.Remove(layout.path(ocspFileName))
+		}
+	}
+
+	// 只生成不含私钥的额外格式（配置校验时已拒绝 pkcs12、jks 和 combined）
+	public := &provider.Certificate{Certificate: cert.Certificate, Chain: cert.Chain}
+	files, err := renderFormats(domain, public, s.settings.formats(domain))
+	if err != nil {
+		return fmt.Errorf("生成证书格式失败: %w", err)
+	}
+	files = append([]formatFile{
+		{name: "cert.pem", data: []byte(cert.Certificate)},
+		{name: "fullchain.pem", data: []byte(fullchain(cert))},
+		{name: metaFileName, data: encodeMetadata(meta)},
+	}, files...)
+
+	for _, file := range files {
+		path := filepath.Join(paths.Dir, file.name)
+		if err := writeFileAtomic(path, file.data, layout.perm(file.name)); err != nil {
+			return fmt.Errorf("保存 %s 失败: %w", file.name, err)
+		}
+		if err := layout.apply(path, layout.perm(file.name)); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(filepath.Join(paths.Dir, "key.pem")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("清理本地私钥失败: %w", err)
+	}
+
+	if err := layout.publish(files); err != nil {
+		return fmt.Errorf("发布证书文件失败: %w", err)
+	}
+	return nil
+}
+
+// readSecret 读取证书的指定版本，version 为 0 时读取当前版本
+func (s *VaultStorage) readSecret(domain string, version int) (*vaultSecret, error) {
+	secretPath, err := s.secretPath(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	apiPath := s.apiPath("data", secretPath)
+	if version > 0 {
+		apiPath += "?version=" + strconv.Itoa(version)
+	}
+	resp, err := s.do(http.MethodGet, apiPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret vaultSecret
+	if err := json.Unmarshal(resp, &secret); err != nil {
+		return nil, fmt.Errorf("解析 Vault 响应失败: %w", err)
+	}
+	// 当前版本已删除时 Vault 返回 404 或空数据
+	if secret.Data == nil {
+		return nil, &vaultError{StatusCode: http.StatusNotFound}
+	}
+	return &secret, nil
+}
+
+// secretPath 根据路径模板生成域名的密钥路径
+func (s *VaultStorage) secretPath(domain string) (string, error) {
+	var buf bytes.Buffer
+	if err := s.path.Execute(&buf, map[string]string{"Domain": domain}); err != nil {
+		return "", fmt.Errorf("生成 Vault 路径失败: %w", err)
+	}
+	return strings.Trim(buf.String(), "/"), nil
+}
+
+// mount 返回 KV v2 挂载路径
+func (s *VaultStorage) mount() string {
+	if s.cfg.Mount == "" {
+		return "secret"
+	}
+	return strings.Trim(s.cfg.Mount, "/")
+}
+
+// apiPath 生成 KV v2 API 路径，kind 为 data 或 metadata
+func (s *VaultStorage) apiPath(kind, secretPath string) string {
+	return "/v1/" + s.mount() + "/" + kind + "/" + secretPath
+}
+
+// do 发送已认证的请求并返回响应中的 data 字段，token 失效时重新登录并重试一次
+func (s *VaultStorage) do(method, apiPath string, body interface{}) (json.RawMessage, error) {
+	token, err := s.getToken(false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.request(method, apiPath, token, body)
+	var vaultErr *vaultError
+	if errors.As(err, &vaultErr) && vaultErr.StatusCode == http.StatusForbidden && s.authMethod() != "token" {
+		if token, err = s.getToken(true); err != nil {
+			return nil, err
+		}
+		resp, err = s.request(method, apiPath, token, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// vaultResponse Vault API 响应
+type vaultResponse struct {
+	Data json.RawMessage `json:"data"`
+	Auth *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+}
+
+// request 发送请求，token 为空时不携带认证头（用于登录）
+func (s *VaultStorage) request(method, apiPath, token string, body interface{}) (*vaultResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("序列化请求失败: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(s.cfg.Address, "/")+apiPath, reader)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if s.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.cfg.Namespace)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Vault 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		vaultErr := &vaultError{StatusCode: resp.StatusCode}
+		json.Unmarshal(data, vaultErr)
+		return nil, vaultErr
+	}
+
+	var result vaultResponse
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("解析 Vault 响应失败: %w", err)
+		}
+	}
+	return &result, nil
+}
+
+// authMethod 返回认证方式，默认 token
+func (s *VaultStorage) authMethod() string {
+	if s.cfg.Auth.Method == "" {
+		return "token"
+	}
+	return s.cfg.Auth.Method
+}
+
+// getToken 返回可用的 token，必要时（或 force 为 true 时）重新登录
+func (s *VaultStorage) getToken(force bool) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	auth := s.cfg.Auth
+	if s.authMethod() == "token" {
+		if auth.Token != "" {
+			return auth.Token, nil
+		}
+		if token := os.Getenv("VAULT_TOKEN"); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("未配置 Vault token")
+	}
+
+	// 提前 30 秒续登，避免请求途中过期
+	if !force && s.token != "" && (s.tokenExpiry.IsZero() || time.Now().Add(30*time.Second).Before(s.tokenExpiry)) {
+		return s.token, nil
+	}
+
+	var body map[string]string
+	switch s.authMethod() {
+	case "approle":
+		body = map[string]string{"role_id": auth.RoleID, "secret_id": auth.SecretID}
+	case "kubernetes":
+		jwtPath := auth.JWTPath
+		if jwtPath == "" {
+			jwtPath = defaultKubernetesJWTPath
+		}
+		jwt, err := os.ReadFile(jwtPath)
+		if err != nil {
+			return "", fmt.Errorf("读取 ServiceAccount token 失败: %w", err)
+		}
+		body = map[string]string{"role": auth.Role, "jwt": strings.TrimSpace(string(jwt))}
+	default:
+		return "", fmt.Errorf("不支持的 Vault 认证方式: %s", s.authMethod())
+	}
+
+	mountPath := auth.MountPath
+	if mountPath == "" {
+		mountPath = s.authMethod()
+	}
+	resp, err := s.request(http.MethodPost, "/v1/auth/"+strings.Trim(mountPath, "/")+"/login", "", body)
+	if err != nil {
+		return "", fmt.Errorf("Vault 登录失败: %w", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("Vault 登录失败: 响应中没有 token")
+	}
+
+	s.token = resp.Auth.ClientToken
+	s.tokenExpiry = time.Time{}
+	if resp.Auth.LeaseDuration > 0 {
+		s.tokenExpiry = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second)
+	}
+	return s.token, nil
+}
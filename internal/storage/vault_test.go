@@ -0,0 +1,343 @@
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ssl-manager/internal/certutil"
+	"ssl-manager/internal/config"
+)
+
+// fakeVault 测试用 Vault 服务，支持 approle/kubernetes 登录和 KV v2 的读写、元数据、列表和删除（挂载路径为 secret）
+type fakeVault struct {
+	mu       sync.Mutex
+	tokens   map[string]bool                         // 有效的 token
+	logins   []map[string]string                     // 收到的登录请求（含 path）
+	secrets  map[string]*fakeVaultKV                 // 密钥路径 -> 数据
+	loginFor map[string]func(map[string]string) bool // 登录路径 -> 校验凭证
+}
+
+// fakeVaultKV KV v2 单个密钥的所有版本
+type fakeVaultKV struct {
+	versions []map[string]string
+	created  []time.Time
+	deleted  map[int]bool
+	custom   map[string]string
+}
+
+func newFakeVault() *fakeVault {
+	return &fakeVault{
+		tokens:   map[string]bool{"root-token": true},
+		secrets:  map[string]*fakeVaultKV{},
+		loginFor: map[string]func(map[string]string) bool{},
+	}
+}
+
+// revokeAll 使所有 token 失效，模拟 token 过期
+func (f *fakeVault) revokeAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens = map[string]bool{}
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	if strings.HasPrefix(r.URL.Path, "/v1/auth/") {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		body["path"] = r.URL.Path
+		f.logins = append(f.logins, body)
+		check, ok := f.loginFor[r.URL.Path]
+		if !ok || !check(body) {
+			writeVault(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid credentials"}})
+			return
+		}
+		token := "login-token-" + strconv.Itoa(len(f.logins))
+		f.tokens[token] = true
+		writeVault(w, http.StatusOK, map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": token, "lease_duration": 3600},
+		})
+		return
+	}
+
+	if !f.tokens[r.Header.Get("X-Vault-Token")] {
+		writeVault(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+
+	if path, ok := strings.CutPrefix(r.URL.Path, "/v1/secret/data/"); ok {
+		f.serveData(w, r, path)
+		return
+	}
+	if path, ok := strings.CutPrefix(r.URL.Path, "/v1/secret/metadata/"); ok {
+		f.serveMetadata(w, r, path)
+		return
+	}
+	writeVault(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+}
+
+func (f *fakeVault) serveData(w http.ResponseWriter, r *http.Request, path string) {
+	kv := f.secrets[path]
+	switch r.Method {
+	case http.MethodPost:
+		var body struct {
+			Data map[string]string `json:"data"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if kv == nil {
+			kv = &fakeVaultKV{deleted: map[int]bool{}}
+			f.secrets[path] = kv
+		}
+		kv.versions = append(kv.versions, body.Data)
+		kv.created = append(kv.created, time.Now())
+		writeVault(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"version": len(kv.versions)}})
+	case http.MethodGet:
+		if kv == nil {
+			writeVault(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		version := len(kv.versions)
+		if v := r.URL.Query().Get("version"); v != "" {
+			version, _ = strconv.Atoi(v)
+		}
+		if version < 1 || version > len(kv.versions) || kv.deleted[version] {
+			writeVault(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		writeVault(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"data":     kv.versions[version-1],
+			"metadata": map[string]interface{}{"version": version, "created_time": kv.created[version-1]},
+		}})
+	case http.MethodDelete:
+		if kv != nil {
+			kv.deleted[len(kv.versions)] = true
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeVault) serveMetadata(w http.ResponseWriter, r *http.Request, path string) {
+	if r.URL.Query().Get("list") == "true" {
+		prefix := strings.TrimSuffix(path, "/") + "/"
+		keys := map[string]bool{}
+		for name := range f.secrets {
+			if rest, ok := strings.CutPrefix(name, prefix); ok {
+				if dir, _, found := strings.Cut(rest, "/"); found {
+					keys[dir+"/"] = true
+				} else {
+					keys[rest] = true
+				}
+			}
+		}
+		if len(keys) == 0 {
+			writeVault(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		var list []string
+		for key := range keys {
+			list = append(list, key)
+		}
+		sort.Strings(list)
+		writeVault(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"keys": list}})
+		return
+	}
+
+	kv := f.secrets[path]
+	if kv == nil {
+		writeVault(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+	if r.Method == http.MethodPost {
+		var body struct {
+			CustomMetadata map[string]string `json:"custom_metadata"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		kv.custom = body.CustomMetadata
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	versions := map[string]interface{}{}
+	for i, created := range kv.created {
+		deletion := ""
+		if kv.deleted[i+1] {
+			deletion = time.Now().Format(time.RFC3339)
+		}
+		versions[strconv.Itoa(i+1)] = map[string]interface{}{"created_time": created, "deletion_time": deletion, "destroyed": false}
+	}
+	writeVault(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+		"current_version": len(kv.versions),
+		"custom_metadata": kv.custom,
+		"versions":        versions,
+	}})
+}
+
+func writeVault(w http.ResponseWriter, status int, body interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// newFakeVaultStorage 启动 fakeVault 并创建使用 auth 认证的 Vault 存储
+func newFakeVaultStorage(t *testing.T, auth config.VaultAuthConfig) (*VaultStorage, *fakeVault) {
+	t.Helper()
+	fake := newFakeVault()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewVaultStorage(&config.VaultConfig{Address: server.URL, Auth: auth}, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, fake
+}
+
+func TestVaultStorageTokenRoundTrip(t *testing.T) {
+	store, fake := newFakeVaultStorage(t, config.VaultAuthConfig{Token: "root-token"})
+	cert := testCertificate(t, "www.example.com")
+
+	result, err := store.SaveCertificate("www.example.com", cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Changed {
+		t.Error("首次保存应标记为已变化")
+	}
+
+	loaded, err := store.LoadCertificate("www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Certificate != cert.Certificate || loaded.PrivateKey != cert.PrivateKey {
+		t.Error("读取的证书与保存的不一致")
+	}
+	if result, err := store.SaveCertificate("www.example.com", cert); err != nil || result.Changed {
+		t.Errorf("重复保存: changed = %v, err = %v", result != nil && result.Changed, err)
+	}
+
+	// 私钥只保存在 Vault 中，本地缓存只有证书
+	paths := store.Paths("www.example.com")
+	if _, err := os.Stat(filepath.Join(paths.Dir, "key.pem")); !os.IsNotExist(err) {
+		t.Errorf("本地缓存中存在私钥: %v", err)
+	}
+	if data, err := os.ReadFile(paths.Cert); err != nil || string(data) != cert.Certificate {
+		t.Errorf("本地缓存的证书不一致: %v", err)
+	}
+
+	domains, err := store.ListDomains()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 1 || domains[0] != "www.example.com" {
+		t.Errorf("ListDomains = %v", domains)
+	}
+
+	if err := store.DeleteCertificate("www.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.LoadCertificate("www.example.com"); !isVaultNotFound(err) {
+		t.Errorf("删除后读取: err = %v", err)
+	}
+	if len(fake.logins) != 0 {
+		t.Errorf("token 认证不应登录: %v", fake.logins)
+	}
+}
+
+func TestVaultStorageCustomMetadataAndHistory(t *testing.T) {
+	store, fake := newFakeVaultStorage(t, config.VaultAuthConfig{Token: "root-token"})
+	first := testCertificate(t, "www.example.com")
+	second := testCertificateFor(t, "www.example.com", 48*time.Hour)
+	if _, err := store.SaveCertificate("www.example.com", first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveCertificate("www.example.com", second); err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := certutil.ParseCertificatePEM(second.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := fake.secrets["ssl-manager/www.example.com"].custom
+	want := map[string]string{
+		"domain":      "www.example.com",
+		"serial":      certutil.SerialHex(leaf),
+		"fingerprint": certutil.Fingerprint(leaf),
+		"not_after":   leaf.NotAfter.UTC().Format(time.RFC3339),
+	}
+	for key, value := range want {
+		if custom[key] != value {
+			t.Errorf("custom_metadata[%s] = %q, 期望 %q", key, custom[key], value)
+		}
+	}
+
+	versions, err := store.History("www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || !versions[0].Current || versions[0].Serial != certutil.SerialHex(leaf) || versions[1].Current {
+		t.Fatalf("History = %+v", versions)
+	}
+}
+
+func TestVaultStorageAppRoleLogin(t *testing.T) {
+	store, fake := newFakeVaultStorage(t, config.VaultAuthConfig{Method: "approle", RoleID: "role", SecretID: "secret"})
+	fake.loginFor["/v1/auth/approle/login"] = func(body map[string]string) bool {
+		return body["role_id"] == "role" && body["secret_id"] == "secret"
+	}
+
+	if _, err := store.SaveCertificate("www.example.com", testCertificate(t, "www.example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.logins) != 1 {
+		t.Fatalf("登录次数 = %d, 期望 1（token 应被复用）", len(fake.logins))
+	}
+
+	// token 失效后返回 403，重新登录并重试
+	fake.revokeAll()
+	if _, err := store.LoadCertificate("www.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.logins) != 2 {
+		t.Fatalf("token 失效后登录次数 = %d, 期望 2", len(fake.logins))
+	}
+}
+
+func TestVaultStorageKubernetesLogin(t *testing.T) {
+	jwtPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(jwtPath, []byte("service-account-jwt\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, fake := newFakeVaultStorage(t, config.VaultAuthConfig{
+		Method: "kubernetes", MountPath: "k8s-prod", Role: "ssl-manager", JWTPath: jwtPath,
+	})
+	fake.loginFor["/v1/auth/k8s-prod/login"] = func(body map[string]string) bool {
+		return body["role"] == "ssl-manager" && body["jwt"] == "service-account-jwt"
+	}
+
+	if _, err := store.SaveCertificate("www.example.com", testCertificate(t, "www.example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.logins) != 1 || fake.logins[0]["path"] != "/v1/auth/k8s-prod/login" {
+		t.Fatalf("登录请求 = %v", fake.logins)
+	}
+}
+
+func TestVaultStorageLoginFailed(t *testing.T) {
+	store, _ := newFakeVaultStorage(t, config.VaultAuthConfig{Method: "approle", RoleID: "role", SecretID: "wrong"})
+	_, err := store.LoadCertificate("www.example.com")
+	if err == nil || !strings.Contains(err.Error(), "Vault 登录失败") {
+		t.Fatalf("错误的凭证: err = %v", err)
+	}
+}